@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
 	"github.com/rwberendsen/grupr/internal/snowflake"
+	"github.com/rwberendsen/grupr/internal/syntax"
 )
 
 func main() {
-	// oldFlag := flag.String("o", "", "old YAML, if any") // TODO: grupinDiff needs work
+	oldFlag := flag.String("o", "", "old YAML, if any; products that start consuming deprecated interfaces are refused only if this is given")
+	autoSuspendFlag := flag.Bool("auto-suspend", false, "suspend running pipes and tasks without prompting, to transfer their ownership")
+	listExpiringFlag := flag.Int("list-expiring", 0, "list team memberships that end within this number of days, and exit")
+	listPendingFlag := flag.Bool("list-pending", false, "list consumption of interfaces that the producing product did not approve, and exit")
+	dropWarehousesFlag := flag.Bool("drop-warehouses", false, "drop warehouses owned by grupr that are no longer declared in the Snowflake YAML")
+	confiscateAfterFlag := flag.Int("confiscate-after", 0, "revoke product write roles from legacy owners of objects this number of days after granting them, unless a product sets confiscate_after")
 	flag.Parse()
 	if len(flag.Args()) < 1 || len(flag.Args()) > 2 {
 		log.Fatalf("usage: grupr path_to_yaml [path_to_snowflake_yaml]")
@@ -50,8 +58,49 @@ func main() {
 		log.Fatalf("get new grupin: %v", err)
 	}
 	log.Println("Deserialized YAML")
+	for _, e := range newGrupin.Policies.Violations {
+		log.Printf("WARN: policy violation: %v", e)
+	}
+	deprecatedConsumptions := newGrupin.DeprecatedConsumptions()
+	for _, c := range deprecatedConsumptions {
+		log.Printf("WARN: product '%s' consumes interface '%s' of product '%s', which is deprecated; sunset date: %s",
+			c.ConsumerID, c.ID, c.ProductID, c.SunsetDate)
+	}
+	if len(deprecatedConsumptions) > 0 && *oldFlag == "" {
+		// Without the old YAML we can not tell existing consumers from new ones
+		log.Printf("WARN: no old YAML given with -o, not checking for new consumers of deprecated interfaces")
+	}
+
+	// List before enforcing anything, so that what needs attention can be listed, also when the YAML is refused
+	if *listExpiringFlag > 0 {
+		for _, m := range newGrupin.ExpiringMemberships(time.Now(), *listExpiringFlag) {
+			fmt.Printf("%s\t%s\t%s\t%s\n", m.LastDay(), m.TeamID, m.Member, m.Reason)
+		}
+		return
+	}
+	if *listPendingFlag {
+		for _, e := range newGrupin.PendingConsumptions(time.Now()) {
+			fmt.Printf("%s\t%s\t%s\n", e.ProductID, e.ID, e.ConsumerID)
+		}
+		return
+	}
 
-	/* TODO: consider implementing GrupinDiff
+	if *oldFlag != "" {
+		f, err := os.Open(*oldFlag)
+		if err != nil {
+			log.Fatalf("open old yaml: %v", err)
+		}
+		oldGrupin, err := syntax.NewGrupin(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("get old grupin: %v", err)
+		}
+		if err := newGrupin.CheckNewConsumption(oldGrupin); err != nil {
+			log.Fatalf("check new consumption: %v", err)
+		}
+	}
+
+	/* TODO: consider implementing GrupinDiff; for now, the old YAML is only used to check new consumption
 	if *oldFlag != "" {
 		oldGrupin, err := util.GetGrupinFromPath(*oldFlag)
 		if err != nil {
@@ -83,6 +132,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("get snowflake config: %v", err)
 	}
+	snowCnf.AutoSuspend = *autoSuspendFlag
+	snowCnf.DropWarehouses = *dropWarehousesFlag
+	snowCnf.ConfiscateAfter = time.Duration(*confiscateAfterFlag) * 24 * time.Hour
 
 	conn, err := snowflake.GetDB(ctx, snowCnf)
 	if err != nil {
@@ -90,6 +142,11 @@ func main() {
 	}
 	log.Println("Connected to the database")
 
+	// Preflight checks, that only warn about the account; if grupr can not check, that is no reason to stop either
+	if err := snowflake.CheckAccountSessionPolicy(ctx, snowCnf, conn); err != nil {
+		log.Printf("WARN: CheckAccountSessionPolicy: %v", err)
+	}
+
 	// Create Snowflake Grupin object, which will hold, later, all relevant account objects per data product
 	// Still, this call already initializes the account cache, which will already have all databases that exist,
 	// and the database roles that grupr is managing; we might move this initialisation to the ManageAccess call,
@@ -112,6 +169,16 @@ func main() {
 		log.Fatalf("StoreObjectCounts: %v", err)
 	}
 
+	// And report the ownership transfers, which are the most disruptive thing grupr does
+	if err := snowflake.StoreOwnershipTransferRows(ctx, snowCnf, conn, snowflakeNewGrupin.GetOwnershipTransferRows()); err != nil {
+		log.Fatalf("StoreOwnershipTransfers: %v", err)
+	}
+
+	// As well as the credits used by products against their quota, if any
+	if err := snowflake.StoreResourceMonitorUsageRows(ctx, snowCnf, conn, snowflakeNewGrupin.GetResourceMonitorUsageRows(ctx, conn)); err != nil {
+		log.Fatalf("StoreResourceMonitorUsage: %v", err)
+	}
+
 	// TODO: also think about how to guard against an error scenario in which someone triggers an old grupr run in CI/CD, e.g., we could store a UUID, or even a git hash
 	// in the Grupr schema of the currently running run; the last thing Grupr would always try before crashing is to wipe that one; but, it'd mean from time to time ops may have
 	// to come in and delete that one; but imagine the bewilderment if two grupr processes are concurrently trying to make two different yamls the reality...
@@ -86,7 +86,7 @@ func (c ColExpr) subsetOfObjExprs(objExprs iter.Seq[ObjExpr]) bool {
 	return objExpr.subsetOfObjExprs(objExprs)
 }
 
-func (c ColExpr) disjointWithObjExpr(e ObjExpr) bool {
+func (c ColExpr) DisjointWithObjExpr(e ObjExpr) bool {
 	objExpr := ObjExpr{c[0], c[1], c[2]}
 	return objExpr.disjoint(e)
 }
@@ -94,7 +94,7 @@ func (c ColExpr) disjointWithObjExpr(e ObjExpr) bool {
 func (c ColExpr) disjointWithObjMatchers(oms ObjMatchers, dtap string) bool {
 	for _, om := range oms {
 		if om.DTAP == dtap {
-			if !c.disjointWithObjExpr(om.Include) {
+			if !c.DisjointWithObjExpr(om.Include) {
 				if !c.subsetOfObjExprs(maps.Keys(om.Exclude)) {
 					return false
 				}
@@ -104,6 +104,14 @@ func (c ColExpr) disjointWithObjMatchers(oms ObjMatchers, dtap string) bool {
 	return true
 }
 
+func (e ColExpr) MatchObject(db Ident, schema Ident, obj Ident) bool {
+	return e.Database().Match(db) && e.Schema().Match(schema) && e.Object().Match(obj)
+}
+
+func (e ColExpr) MatchColumn(db Ident, schema Ident, obj Ident, col Ident) bool {
+	return e.MatchObject(db, schema, obj) && e.Column().Match(col)
+}
+
 func (e ColExpr) Database() IdentMatcher {
 	return e[0]
 }
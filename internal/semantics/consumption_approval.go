@@ -0,0 +1,130 @@
+package semantics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+// A ConsumptionEdge is a product consuming an interface of another product
+type ConsumptionEdge struct {
+	syntax.InterfaceID
+	ConsumerID string
+}
+
+// A ConsumptionApproval by the producing product; a zero Until means the approval does not expire. Until is
+// exclusive, it is the start of the day after the last day of the approval, UTC.
+type ConsumptionApproval struct {
+	ApprovedBy Ident
+	Until      time.Time
+	Reason     string
+}
+
+func (a ConsumptionApproval) IsActive(t time.Time) bool {
+	return a.Until.IsZero() || t.Before(a.Until)
+}
+
+// newConsumptionApprovals validates approvals; they may be recorded before the consumer starts consuming, but the
+// approver should be a member of a team that works on the producing product
+func (g Grupin) newConsumptionApprovals(cnf *Config, approvalsSyn []syntax.ConsumptionApproval) (map[ConsumptionEdge]ConsumptionApproval, error) {
+	m := map[ConsumptionEdge]ConsumptionApproval{}
+	for _, aSyn := range approvalsSyn {
+		e := ConsumptionEdge{InterfaceID: aSyn.InterfaceID, ConsumerID: aSyn.Consumer}
+		pSource, ok := g.Products[e.ProductID]
+		if !ok {
+			return m, &SetLogicError{fmt.Sprintf("consumption_approval: interface '%s' of product '%s': product not found", e.ID, e.ProductID)}
+		}
+		if _, ok := pSource.Interfaces[e.ID]; !ok {
+			return m, &SetLogicError{fmt.Sprintf("consumption_approval: interface '%s' of product '%s': interface not found", e.ID, e.ProductID)}
+		}
+		if _, ok := g.Products[e.ConsumerID]; !ok {
+			return m, &SetLogicError{fmt.Sprintf("consumption_approval: interface '%s' of product '%s': consumer '%s': product not found", e.ID, e.ProductID, e.ConsumerID)}
+		}
+		if _, ok := m[e]; ok {
+			return m, fmt.Errorf("consumption_approval: interface '%s' of product '%s': duplicate approval for consumer '%s'", e.ID, e.ProductID, e.ConsumerID)
+		}
+		a := ConsumptionApproval{Reason: aSyn.Reason}
+		ident, err := NewIdentStripQuotesIfAny(aSyn.ApprovedBy, cnf.ValidQuotedExpr, cnf.ValidUnquotedExpr)
+		if err != nil {
+			return m, fmt.Errorf("consumption_approval: interface '%s' of product '%s': approved_by: %w", e.ID, e.ProductID, err)
+		}
+		if !g.worksOn(ident, e.ProductID) {
+			return m, &PolicyError{s: fmt.Sprintf("consumption_approval: interface '%s' of product '%s': approved_by '%v': not a member of a team that works on the product",
+				e.ID, e.ProductID, ident)}
+		}
+		a.ApprovedBy = ident
+		if aSyn.Until != "" {
+			t, err := time.Parse(time.DateOnly, aSyn.Until)
+			if err != nil {
+				return m, fmt.Errorf("consumption_approval: interface '%s' of product '%s': invalid until '%s', use a date like 2006-01-02", e.ID, e.ProductID, aSyn.Until)
+			}
+			a.Until = t.AddDate(0, 0, 1)
+		}
+		m[e] = a
+	}
+	return m, nil
+}
+
+// worksOn returns whether user ident is a member of a team that works on the product; we do not look at the dates
+// of the membership, an approval stays valid when the approver leaves the team
+func (g Grupin) worksOn(ident Ident, productID string) bool {
+	for _, t := range g.Teams {
+		if _, ok := t.WorkOn[productID]; !ok {
+			continue
+		}
+		if _, ok := t.Members[ident]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingConsumptions returns the consumption edges that have no approval that is active at time t
+func (g Grupin) PendingConsumptions(t time.Time) []ConsumptionEdge {
+	r := []ConsumptionEdge{}
+	for _, p := range g.Products {
+		for iid := range p.Consumes {
+			e := ConsumptionEdge{InterfaceID: iid, ConsumerID: p.ID}
+			if a, ok := g.ConsumptionApprovals[e]; !ok || !a.IsActive(t) {
+				r = append(r, e)
+			}
+		}
+	}
+	slices.SortFunc(r, func(a, b ConsumptionEdge) int {
+		if c := strings.Compare(a.ProductID, b.ProductID); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.ID, b.ID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ConsumerID, b.ConsumerID)
+	})
+	return r
+}
+
+// UnapprovedConsumptions returns the consumption edges that have no approval that is active at time t, while the
+// producing product requires it; consumers lose access to these, rather than that the whole YAML is refused, so that
+// an expiring approval only affects the edge it is about
+func (g Grupin) UnapprovedConsumptions(t time.Time) []ConsumptionEdge {
+	r := []ConsumptionEdge{}
+	for _, e := range g.PendingConsumptions(t) {
+		if g.Policies.get(RuleConsumptionApproval, e.ProductID).Level == PolicyErr {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// approvalsOk keeps a violation for every consumption edge that is not approved at time t, if the producing product
+// warns about it; note that the policy is scoped by the producing product, not by the consumer
+func (g *Grupin) approvalsOk(t time.Time) {
+	for _, e := range g.PendingConsumptions(t) {
+		if g.Policies.get(RuleConsumptionApproval, e.ProductID).Level == PolicyWarn {
+			g.Policies.Violations = append(g.Policies.Violations, &PolicyError{RuleID: RuleConsumptionApproval,
+				s: fmt.Sprintf("product '%s' consumes interface '%s' of product '%s' without its approval", e.ConsumerID, e.ID, e.ProductID)})
+		}
+	}
+}
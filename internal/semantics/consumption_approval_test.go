@@ -0,0 +1,176 @@
+package semantics
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func newApprovalGrupin() Grupin {
+	return Grupin{
+		Products: map[string]Product{
+			"src": Product{
+				ID:         "src",
+				Interfaces: map[string]InterfaceMetadata{"i": InterfaceMetadata{}, "j": InterfaceMetadata{}},
+			},
+			"dst": Product{
+				ID: "dst",
+				Consumes: map[syntax.InterfaceID]map[string]string{
+					syntax.InterfaceID{ID: "i", ProductID: "src"}: map[string]string{},
+					syntax.InterfaceID{ID: "j", ProductID: "src"}: map[string]string{},
+				},
+			},
+		},
+		Teams: map[string]Team{
+			"producers": Team{
+				ID:      "producers",
+				Members: map[Ident]Membership{"ALICE": Membership{}},
+				WorkOn:  map[string]struct{}{"src": {}},
+			},
+			"consumers": Team{
+				ID:      "consumers",
+				Members: map[Ident]Membership{"BOB": Membership{}},
+				WorkOn:  map[string]struct{}{"dst": {}},
+			},
+		},
+	}
+}
+
+func TestNewConsumptionApprovals(t *testing.T) {
+	cnf, err := GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	g := newApprovalGrupin()
+	i := syntax.InterfaceID{ID: "i", ProductID: "src"}
+	tests := []struct {
+		approvals []syntax.ConsumptionApproval
+		want      map[ConsumptionEdge]ConsumptionApproval
+		wantErr   bool
+	}{
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: i, Consumer: "dst", ApprovedBy: "ALICE", Until: "2025-12-31", Reason: "r"}},
+			want: map[ConsumptionEdge]ConsumptionApproval{
+				ConsumptionEdge{InterfaceID: i, ConsumerID: "dst"}: ConsumptionApproval{ApprovedBy: "ALICE", Until: date("2026-01-01"), Reason: "r"},
+			},
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: i, Consumer: "dst", ApprovedBy: "BOB"}},
+			wantErr:   true, // does not work on the producing product
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: syntax.InterfaceID{ID: "k", ProductID: "src"}, Consumer: "dst", ApprovedBy: "ALICE"}},
+			wantErr:   true,
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: syntax.InterfaceID{ID: "i", ProductID: "nope"}, Consumer: "dst", ApprovedBy: "ALICE"}},
+			wantErr:   true,
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: i, Consumer: "nope", ApprovedBy: "ALICE"}},
+			wantErr:   true,
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{{InterfaceID: i, Consumer: "dst", ApprovedBy: "ALICE", Until: "31-12-2025"}},
+			wantErr:   true,
+		},
+		{
+			approvals: []syntax.ConsumptionApproval{
+				{InterfaceID: i, Consumer: "dst", ApprovedBy: "ALICE"},
+				{InterfaceID: i, Consumer: "dst", ApprovedBy: "ALICE", Until: "2025-12-31"},
+			},
+			wantErr: true,
+		},
+	}
+	for n, test := range tests {
+		got, err := g.newConsumptionApprovals(cnf, test.approvals)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: newConsumptionApprovals(%v): expected an error", n, test.approvals)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: newConsumptionApprovals(%v): %v", n, test.approvals, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("test %d: newConsumptionApprovals(%v) = %v, want %v", n, test.approvals, got, test.want)
+		}
+		for e, a := range test.want {
+			if b, ok := got[e]; !ok || b.ApprovedBy != a.ApprovedBy || !b.Until.Equal(a.Until) || b.Reason != a.Reason {
+				t.Errorf("test %d: newConsumptionApprovals(%v): edge %v: got %v, want %v", n, test.approvals, e, b, a)
+			}
+		}
+	}
+}
+
+func TestPendingConsumptions(t *testing.T) {
+	g := newApprovalGrupin()
+	i := ConsumptionEdge{InterfaceID: syntax.InterfaceID{ID: "i", ProductID: "src"}, ConsumerID: "dst"}
+	j := ConsumptionEdge{InterfaceID: syntax.InterfaceID{ID: "j", ProductID: "src"}, ConsumerID: "dst"}
+	g.ConsumptionApprovals = map[ConsumptionEdge]ConsumptionApproval{
+		i: ConsumptionApproval{ApprovedBy: "ALICE", Until: date("2026-01-01")},
+	}
+	tests := []struct {
+		t    string
+		want []ConsumptionEdge
+	}{
+		{t: "2025-12-31", want: []ConsumptionEdge{j}},
+		{t: "2026-01-01", want: []ConsumptionEdge{i, j}},
+	}
+	for _, test := range tests {
+		if got := g.PendingConsumptions(date(test.t)); !slices.Equal(got, test.want) {
+			t.Errorf("PendingConsumptions(%s) = %v, want %v", test.t, got, test.want)
+		}
+	}
+}
+
+func TestUnapprovedConsumptions(t *testing.T) {
+	j := ConsumptionEdge{InterfaceID: syntax.InterfaceID{ID: "j", ProductID: "src"}, ConsumerID: "dst"}
+	tests := []struct {
+		policies       []syntax.Policy
+		want           []ConsumptionEdge
+		wantViolations int
+	}{
+		{
+			want: []ConsumptionEdge{},
+		},
+		{
+			policies:       []syntax.Policy{{Rule: RuleConsumptionApproval, Level: "warn"}},
+			want:           []ConsumptionEdge{},
+			wantViolations: 1,
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleConsumptionApproval, Level: "error"}},
+			want:     []ConsumptionEdge{j},
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleConsumptionApproval, Level: "error", Products: []string{"dst"}}},
+			want:     []ConsumptionEdge{}, // scoped by the producing product
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleConsumptionApproval, Level: "error", Products: []string{"src"}}},
+			want:     []ConsumptionEdge{j},
+		},
+	}
+	for n, test := range tests {
+		g := newApprovalGrupin()
+		g.ConsumptionApprovals = map[ConsumptionEdge]ConsumptionApproval{
+			ConsumptionEdge{InterfaceID: syntax.InterfaceID{ID: "i", ProductID: "src"}, ConsumerID: "dst"}: ConsumptionApproval{ApprovedBy: "ALICE"},
+		}
+		ps, err := newPolicies(test.policies, g.Products)
+		if err != nil {
+			t.Fatalf("test %d: newPolicies: %v", n, err)
+		}
+		g.Policies = ps
+		g.approvalsOk(date("2025-06-01"))
+		if len(g.Policies.Violations) != test.wantViolations {
+			t.Errorf("test %d: approvalsOk: %d violations, want %d", n, len(g.Policies.Violations), test.wantViolations)
+		}
+		if got := g.UnapprovedConsumptions(date("2025-06-01")); !slices.Equal(got, test.want) {
+			t.Errorf("test %d: UnapprovedConsumptions() = %v, want %v", n, got, test.want)
+		}
+	}
+}
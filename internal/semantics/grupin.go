@@ -3,17 +3,21 @@ package semantics
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/syntax"
 )
 
 type Grupin struct {
-	Classes           map[string]syntax.Class
-	GlobalUserGroups  GlobalUserGroups
-	UserGroupMappings map[string]UserGroupMapping
-	Products          map[string]Product
-	ServiceAccounts   map[string]ServiceAccount
-	Teams             map[string]Team
+	Classes              map[string]syntax.Class
+	GlobalUserGroups     GlobalUserGroups
+	UserGroupMappings    map[string]UserGroupMapping
+	Products             map[string]Product
+	ServiceAccounts      map[string]ServiceAccount
+	Teams                map[string]Team
+	Policies             Policies
+	ConsumptionApprovals map[ConsumptionEdge]ConsumptionApproval
 }
 
 func NewGrupin(cnf *Config, gSyn syntax.Grupin) (Grupin, error) {
@@ -65,7 +69,7 @@ func NewGrupin(cnf *Config, gSyn syntax.Grupin) (Grupin, error) {
 	// Validate interface specs
 	for iid, v := range gSyn.Interfaces {
 		if _, err := NewID(cnf, iid.ID); err != nil {
-			return gSem, &SetLogicError{fmt.Sprintf("interface id '%s' its ID field: %w", iid, err)}
+			return gSem, &SetLogicError{fmt.Sprintf("interface id '%s' its ID field: %v", iid, err)}
 		}
 		if parentProduct, ok := gSem.Products[iid.ProductID]; !ok {
 			return gSem, &SetLogicError{fmt.Sprintf("interface id '%s': product not found", iid)}
@@ -74,11 +78,14 @@ func NewGrupin(cnf *Config, gSyn syntax.Grupin) (Grupin, error) {
 			userGroupMapping := gSem.UserGroupMappings[parentProduct.UserGroupMappingID]
 			userGroupRenderings := parentProduct.UserGroupRenderings
 			parent := parentProduct.InterfaceMetadata
-			if im, err := newInterfaceMetadata(cnf, v.InterfaceMetadata, gSem.Classes, ds, userGroupMapping, userGroupRenderings, &parent); err != nil {
+			im, err := newInterfaceMetadata(cnf, v.InterfaceMetadata, gSem.Classes, ds, userGroupMapping, userGroupRenderings, &parent)
+			if err != nil {
 				return gSem, fmt.Errorf("interface '%s': %w", iid, err)
-			} else {
-				parentProduct.Interfaces[iid.ID] = im
 			}
+			if err := im.setLifecycle(v); err != nil {
+				return gSem, fmt.Errorf("interface '%s': %w", iid, err)
+			}
+			parentProduct.Interfaces[iid.ID] = im
 		}
 	}
 	// Validate DTAP and UserGroup tagging
@@ -87,7 +94,12 @@ func NewGrupin(cnf *Config, gSyn syntax.Grupin) (Grupin, error) {
 			return gSem, fmt.Errorf("product '%s': %w", k, err)
 		}
 	}
-	// Validate consume relationships
+	// Validate policies, and then consume relationships, which are subject to them
+	if ps, err := newPolicies(gSyn.Policies, gSem.Products); err != nil {
+		return gSem, err
+	} else {
+		gSem.Policies = ps
+	}
 	if err := gSem.allConsumedOk(); err != nil {
 		return gSem, err
 	}
@@ -115,6 +127,16 @@ func NewGrupin(cnf *Config, gSyn syntax.Grupin) (Grupin, error) {
 	if err := gSem.validateUsers(gSem.ServiceAccounts, gSem.Teams); err != nil {
 		return gSem, err
 	}
+	if err := gSem.teamsOk(); err != nil {
+		return gSem, err
+	}
+	// Validate consumption approvals, which refer to team members
+	if approvals, err := gSem.newConsumptionApprovals(cnf, gSyn.ConsumptionApprovals); err != nil {
+		return gSem, err
+	} else {
+		gSem.ConsumptionApprovals = approvals
+	}
+	gSem.approvalsOk(time.Now())
 
 	return gSem, nil
 }
@@ -130,16 +152,28 @@ func NewGrupinFromPath(cnf *Config, path string) (Grupin, error) {
 	if err != nil {
 		return g, err
 	}
+	// Members sources with a relative path are relative to the YAML file, not to the working directory
+	for _, t := range s.Teams {
+		if t.MembersFrom != nil && t.MembersFrom.Path != "" && !filepath.IsAbs(t.MembersFrom.Path) {
+			t.MembersFrom.Path = filepath.Join(filepath.Dir(path), t.MembersFrom.Path)
+		}
+	}
 	return NewGrupin(cnf, s)
 }
 
 func (g Grupin) validateUsers(svcs map[string]ServiceAccount, teams map[string]Team) error {
 	seen := map[Ident]struct{}{}
 	for _, svc := range svcs {
+		// A service account may use the same user in several dtaps, but users should not be shared between them
+		idents := map[Ident]struct{}{}
 		for _, ident := range svc.Idents {
 			if _, ok := seen[ident]; ok {
 				return fmt.Errorf("duplicate user identifier: '%s'", ident)
 			}
+			idents[ident] = struct{}{}
+		}
+		for ident := range idents {
+			seen[ident] = struct{}{}
 		}
 	}
 	people := map[Ident]struct{}{}
@@ -156,7 +190,7 @@ func (g Grupin) validateUsers(svcs map[string]ServiceAccount, teams map[string]T
 	return nil
 }
 
-func (g Grupin) allConsumedOk() error {
+func (g *Grupin) allConsumedOk() error {
 	for _, p := range g.Products {
 		for iid, dtapMapping := range p.Consumes {
 			pSource, ok := g.Products[iid.ProductID]
@@ -169,31 +203,50 @@ func (g Grupin) allConsumedOk() error {
 					fmt.Sprintf("product '%s': consumed interface '%s': interface not found", p.ID, iid),
 				}
 			}
-			if p.Classification < iSource.Classification {
-				// TODO: consider removing this policy rule, possibly too strict
-				// It might be useful to keep it, if e.g., you are using masking or hashing directives in the YAML,
-				// then when you define those, you lower the classification of the interface accordingly;
-				// you can have a separate interface for the same tables where you do not use such directives, and
-				// where you keep the higher classification.
-				// and then you implement some mechanism by which you make sure if a consumer consumes the
-				// interface with masking or hashing directives, that the consumer indeed does not consume the
-				// unmasked and unhashed data.
-				return &PolicyError{fmt.Sprintf("product '%s' consumes interface with higher classification", p.ID)}
+			if !iSource.mayBeConsumedBy(p.ID) {
+				return &PolicyError{s: fmt.Sprintf("product '%s': consumed interface '%s': not allowed to consume this interface", p.ID, iid)}
+			}
+			if iSource.Lifecycle == LifecycleRetired {
+				return &PolicyError{s: fmt.Sprintf("product '%s': consumes interface '%s', which is retired", p.ID, iid)}
+			}
+			// Interfaces that mask or hash columns may have a lower effective classification; you can have a separate
+			// interface on the same objects without such directives, where you keep the higher classification.
+			if err := g.Policies.check(RuleClassificationOrdering, p.ID, p.Classification >= iSource.EffectiveClassification,
+				"product '%s' consumes interface '%s' with higher classification", p.ID, iid); err != nil {
+				return err
+			}
+			if err := g.Policies.check(RuleClassificationOrdering, p.ID, g.maskedConsumptionOk(p, iid),
+				"product '%s' consumes interface '%s' with masking that lowers its classification, and another interface on the same objects that does not mask the same columns", p.ID, iid); err != nil {
+				return err
+			}
+			if err := g.Policies.check(RuleUserGroupCompatibility, p.ID, g.userGroupsCompatible(p, pSource, iSource),
+				"product '%s' consumes interface '%s' with user groups it does not have", p.ID, iid); err != nil {
+				return err
 			}
 
-			// Check DTAP mapping
-			// TODO: add hide_dtaps to interface metadata, union product level and interface level, and check here that hidden dtaps are not consumed.
+			// Check DTAP mapping; hidden dtaps of the interface can not be consumed
+			sourceHasProd := pSource.DTAPs.HasProd() && !iSource.IsHidden(*pSource.DTAPs.Prod)
 			for dtapSelf, dtapSource := range dtapMapping {
 				if p.DTAPs.IsProd(dtapSelf) {
-					if !pSource.DTAPs.HasProd() {
-						// TODO: when source has hidden dtaps, consider that here, too
-						return &PolicyError{fmt.Sprintf("product '%s': consumed interface '%s': source has no prod dtap", p.ID, iid)}
+					if sourceHasProd && (dtapSource == "" || dtapSource == *pSource.DTAPs.Prod) {
+						dtapSource = *pSource.DTAPs.Prod
+						dtapMapping[dtapSelf] = dtapSource
+					} else {
+						if err := g.Policies.check(RuleProdConsumesProd, p.ID, false,
+							"product '%s': consumed interface '%s': production consumes non-production", p.ID, iid); err != nil {
+							return err
+						}
+						if dtapSource == "" {
+							return &SetLogicError{fmt.Sprintf("product '%s': consumed interface '%s': source has no prod dtap, specify a dtap_mapping for '%s'", p.ID, iid, dtapSelf)}
+						}
 					}
-					dtapSource = *pSource.DTAPs.Prod
-					dtapMapping[dtapSelf] = dtapSource
-				} else if !pSource.DTAPs.HasDTAP(dtapSource) {
+				}
+				if !pSource.DTAPs.HasDTAP(dtapSource) {
 					return &SetLogicError{fmt.Sprintf("product '%s': consumed interface '%s': dtap '%s': dtap not found", p.ID, iid, dtapSource)}
 				}
+				if iSource.IsHidden(dtapSource) {
+					return &SetLogicError{fmt.Sprintf("product '%s': consumed interface '%s': dtap '%s': dtap is hidden", p.ID, iid, dtapSource)}
+				}
 				// Even though iSource is a copy, all copies reference the same map, initialized upon creation by NewInterface
 				// So we can reach into that map here and add an element to it
 				iSource.ConsumedBy[dtapSource][ProductDTAPID{ProductID: p.ID, DTAP: dtapSelf}] = struct{}{}
@@ -205,7 +258,12 @@ func (g Grupin) allConsumedOk() error {
 					return &SetLogicError{fmt.Sprintf("product '%s': interface '%s': product not found", p.ID, id)}
 				}
 				if *im.ForProduct == p.ID {
-					return &PolicyError{fmt.Sprintf("product '%s', interface '%s', ForProduct refers to self, but not allowed to consume own interface", p.ID, id)}
+					return &PolicyError{s: fmt.Sprintf("product '%s', interface '%s', ForProduct refers to self, but not allowed to consume own interface", p.ID, id)}
+				}
+			}
+			for pID := range im.AllowedConsumers {
+				if _, ok := g.Products[pID]; !ok {
+					return &SetLogicError{fmt.Sprintf("product '%s': interface '%s': allowed_consumers: product '%s' not found", p.ID, id, pID)}
 				}
 			}
 		}
@@ -213,6 +271,70 @@ func (g Grupin) allConsumedOk() error {
 	return nil
 }
 
+// maskedConsumptionOk returns whether product p, if it relies on the masking or hashing of consumed interface iid to
+// have a classification low enough, does not also get the unmasked data: all other interfaces on the same objects that
+// p consumes should mask and hash the same columns
+func (g Grupin) maskedConsumptionOk(p Product, iid syntax.InterfaceID) bool {
+	pSource := g.Products[iid.ProductID]
+	i := pSource.Interfaces[iid.ID]
+	if p.Classification >= i.Classification {
+		return true
+	}
+	for jid := range p.Consumes {
+		if jid == iid || jid.ProductID != iid.ProductID {
+			// Products are disjoint, so only interfaces of the same product can be on the same objects
+			continue
+		}
+		j := pSource.Interfaces[jid.ID]
+		if j.ObjectMatchers.disjoint(i.ObjectMatchers) {
+			continue
+		}
+		if !j.MaskColumns.Equal(i.MaskColumns) || !j.HashColumns.Equal(i.HashColumns) {
+			return false
+		}
+	}
+	return true
+}
+
+// userGroupsCompatible returns whether product p has all the user groups of interface i of product pSource, after
+// mapping both to the global user groups; an interface without user groups is compatible with any product
+func (g Grupin) userGroupsCompatible(p Product, pSource Product, i InterfaceMetadata) bool {
+	has := map[string]struct{}{}
+	for ug := range p.UserGroups {
+		has[g.UserGroupMappings[p.UserGroupMappingID][ug]] = struct{}{}
+	}
+	for ug := range i.UserGroups {
+		if _, ok := has[g.UserGroupMappings[pSource.UserGroupMappingID][ug]]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// teamsOk checks the rules that limit how many products teams work on; central teams are only counted for products
+// they list in work_on
+func (g *Grupin) teamsOk() error {
+	maxProducts := g.Policies.get(RuleMaxProductsPerTeam, "").Max
+	nTeams := map[string]int{}
+	for _, t := range g.Teams {
+		if err := g.Policies.check(RuleMaxProductsPerTeam, "", maxProducts == 0 || len(t.WorkOn) <= maxProducts,
+			"team '%s' works on %d products, more than %d", t.ID, len(t.WorkOn), maxProducts); err != nil {
+			return err
+		}
+		for pID := range t.WorkOn {
+			nTeams[pID] += 1
+		}
+	}
+	for pID, n := range nTeams {
+		maxTeams := g.Policies.get(RuleMaxTeamsPerProduct, pID).Max
+		if err := g.Policies.check(RuleMaxTeamsPerProduct, pID, maxTeams == 0 || n <= maxTeams,
+			"product '%s' is worked on by %d teams, more than %d", pID, n, maxTeams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (g Grupin) allDisjoint() error {
 	if len(g.Products) < 2 {
 		return nil
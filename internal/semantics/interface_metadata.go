@@ -3,6 +3,7 @@ package semantics
 import (
 	"fmt"
 	"maps"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/syntax"
 	"github.com/rwberendsen/grupr/internal/util"
@@ -14,8 +15,16 @@ type InterfaceMetadata struct {
 	UserGroups     map[string]struct{}
 	MaskColumns    ColMatcher
 	HashColumns    ColMatcher
-	ConsumedBy     map[string]map[ProductDTAPID]struct{} // will be populated by Grupin.allConsumedOK
-	ForProduct     *string
+	// EffectiveClassification is the classification consumers see, it may be lower than Classification
+	// if columns are masked or hashed
+	EffectiveClassification Classification
+	ConsumedBy              map[string]map[ProductDTAPID]struct{} // will be populated by Grupin.allConsumedOK
+	ForProduct              *string
+	Visibility              Visibility
+	AllowedConsumers        map[string]struct{} // for restricted interfaces
+	HiddenDTAPs             map[string]struct{} // can not be consumed; the union of those of the interface and the product
+	Lifecycle               Lifecycle           // only for interfaces, not on product level
+	Sunset                  time.Time           // for deprecated interfaces, the start of the day after the sunset date, UTC
 }
 
 func newInterfaceMetadata(cnf *Config, imSyn syntax.InterfaceMetadata, classes map[string]syntax.Class, ds DTAPSpec, userGroupMapping UserGroupMapping,
@@ -36,14 +45,24 @@ func newInterfaceMetadata(cnf *Config, imSyn syntax.InterfaceMetadata, classes m
 	if err := imSem.setHashColumns(cnf, imSyn, parent, ds, userGroupRenderings); err != nil {
 		return imSem, err
 	}
+	if err := imSem.setEffectiveClassification(imSyn, parent, classes); err != nil {
+		return imSem, err
+	}
 	if err := imSem.setForProduct(imSyn, parent); err != nil {
 		return imSem, err
 	}
+	if err := imSem.setVisibility(imSyn, parent); err != nil {
+		return imSem, err
+	}
+	if err := imSem.setHiddenDTAPs(imSyn, parent, ds); err != nil {
+		return imSem, err
+	}
 	if parent != nil {
 		imSem.ConsumedBy = map[string]map[ProductDTAPID]struct{}{}
-		// TODO: take into account hidden DTAPs
 		for d, _ := range ds.All() {
-			imSem.ConsumedBy[d] = map[ProductDTAPID]struct{}{} // will be further populated by Grupin.allConsumedOK
+			if _, ok := imSem.HiddenDTAPs[d]; !ok {
+				imSem.ConsumedBy[d] = map[ProductDTAPID]struct{}{} // will be further populated by Grupin.allConsumedOK
+			}
 		}
 	}
 	return imSem, nil
@@ -55,7 +74,7 @@ func (imSem *InterfaceMetadata) setClassification(imSyn syntax.InterfaceMetadata
 			imSem.Classification = parent.Classification
 			return nil
 		}
-		return &PolicyError{"Classfication is a required field on product level"}
+		return &PolicyError{s: "Classfication is a required field on product level"}
 	}
 	if c, err := newClassification(imSyn.Classification, classes); err != nil {
 		return err
@@ -63,7 +82,7 @@ func (imSem *InterfaceMetadata) setClassification(imSyn syntax.InterfaceMetadata
 		imSem.Classification = c
 	}
 	if parent != nil && parent.Classification < imSem.Classification {
-		return &PolicyError{"Classification on interface higher than product classification"}
+		return &PolicyError{s: "Classification on interface higher than product classification"}
 	}
 	return nil
 }
@@ -92,7 +111,7 @@ func (imSem *InterfaceMetadata) setUserGroups(imSyn syntax.InterfaceMetadata, pa
 	imSem.UserGroups = map[string]struct{}{}
 	for _, u := range imSyn.UserGroups {
 		if _, ok := parent.UserGroups[u]; !ok {
-			return &PolicyError{fmt.Sprintf("Interface should not have user group '%s' that product does not have", u)}
+			return &PolicyError{s: fmt.Sprintf("Interface should not have user group '%s' that product does not have", u)}
 		} else {
 			imSem.UserGroups[u] = struct{}{}
 		}
@@ -105,7 +124,7 @@ func (imSem *InterfaceMetadata) setObjectMatchers(cnf *Config, imSyn syntax.Inte
 	if imSyn.Objects == nil {
 		if parent != nil {
 			if len(parent.ObjectMatchers) == 0 {
-				return &PolicyError{"interface on product without objects"}
+				return &PolicyError{s: "interface on product without objects"}
 			}
 			imSem.ObjectMatchers = parent.ObjectMatchers
 			return nil
@@ -118,7 +137,7 @@ func (imSem *InterfaceMetadata) setObjectMatchers(cnf *Config, imSyn syntax.Inte
 	}
 	if parent != nil {
 		if !imSem.ObjectMatchers.subsetOf(parent.ObjectMatchers) {
-			return &PolicyError{"ObjectMatcher should be a subset of parent ObjectMatcher"}
+			return &PolicyError{s: "ObjectMatcher should be a subset of parent ObjectMatcher"}
 		}
 		imSem.ObjectMatchers = imSem.ObjectMatchers.setSubsetOf(parent.ObjectMatchers)
 	}
@@ -127,7 +146,7 @@ func (imSem *InterfaceMetadata) setObjectMatchers(cnf *Config, imSyn syntax.Inte
 
 func (imSem *InterfaceMetadata) setHashColumns(cnf *Config, imSyn syntax.InterfaceMetadata, parent *InterfaceMetadata,
 	ds DTAPSpec, userGroupRenderings map[string]syntax.Rendering) error {
-	if imSyn.MaskColumns == nil {
+	if imSyn.HashColumns == nil {
 		if parent != nil {
 			imSem.HashColumns = parent.HashColumns
 		}
@@ -157,6 +176,51 @@ func (imSem *InterfaceMetadata) setMaskColumns(cnf *Config, imSyn syntax.Interfa
 	return nil
 }
 
+func (imSem *InterfaceMetadata) setEffectiveClassification(imSyn syntax.InterfaceMetadata, parent *InterfaceMetadata, classes map[string]syntax.Class) error {
+	if imSyn.MaskedClassification == "" {
+		if parent != nil && imSyn.Classification == "" && imSyn.MaskColumns == nil && imSyn.HashColumns == nil {
+			// Same classification and directives as the parent, so the same effective classification, too
+			imSem.EffectiveClassification = parent.EffectiveClassification
+			return nil
+		}
+		imSem.EffectiveClassification = imSem.Classification
+		return nil
+	}
+	if len(imSem.MaskColumns.ColExprs) == 0 && len(imSem.HashColumns.ColExprs) == 0 {
+		return &PolicyError{s: "masked_classification without mask_columns or hash_columns"}
+	}
+	if c, err := newClassification(imSyn.MaskedClassification, classes); err != nil {
+		return err
+	} else {
+		imSem.EffectiveClassification = c
+	}
+	if imSem.Classification < imSem.EffectiveClassification {
+		return &PolicyError{s: "masked_classification higher than classification"}
+	}
+	return nil
+}
+
+func (imSem *InterfaceMetadata) setHiddenDTAPs(imSyn syntax.InterfaceMetadata, parent *InterfaceMetadata, ds DTAPSpec) error {
+	imSem.HiddenDTAPs = map[string]struct{}{}
+	if parent != nil {
+		for d := range parent.HiddenDTAPs {
+			imSem.HiddenDTAPs[d] = struct{}{}
+		}
+	}
+	for _, d := range imSyn.HideDTAPs {
+		if !ds.HasDTAP(d) {
+			return &SetLogicError{fmt.Sprintf("hide_dtaps: unknown dtap '%s'", d)}
+		}
+		imSem.HiddenDTAPs[d] = struct{}{}
+	}
+	return nil
+}
+
+func (imSem *InterfaceMetadata) IsHidden(dtap string) bool {
+	_, ok := imSem.HiddenDTAPs[dtap]
+	return ok
+}
+
 func (imSem *InterfaceMetadata) setForProduct(imSyn syntax.InterfaceMetadata, parent *InterfaceMetadata) error {
 	if imSyn.ForProduct == nil {
 		if parent != nil {
@@ -171,9 +235,14 @@ func (imSem *InterfaceMetadata) setForProduct(imSyn syntax.InterfaceMetadata, pa
 func (lhs InterfaceMetadata) Equal(rhs InterfaceMetadata) bool {
 	return lhs.ObjectMatchers.Equal(rhs.ObjectMatchers) &&
 		lhs.Classification == rhs.Classification &&
+		lhs.EffectiveClassification == rhs.EffectiveClassification &&
 		maps.Equal(lhs.UserGroups, rhs.UserGroups) &&
 		lhs.MaskColumns.Equal(rhs.MaskColumns) &&
-		lhs.HashColumns.Equal(rhs.MaskColumns) &&
+		lhs.HashColumns.Equal(rhs.HashColumns) &&
 		maps.EqualFunc(lhs.ConsumedBy, rhs.ConsumedBy, func(l map[ProductDTAPID]struct{}, r map[ProductDTAPID]struct{}) bool { return maps.Equal(l, r) }) &&
-		util.EqualStrPtr(lhs.ForProduct, rhs.ForProduct)
+		util.EqualStrPtr(lhs.ForProduct, rhs.ForProduct) &&
+		lhs.equalVisibility(rhs) &&
+		maps.Equal(lhs.HiddenDTAPs, rhs.HiddenDTAPs) &&
+		lhs.Lifecycle == rhs.Lifecycle &&
+		lhs.Sunset.Equal(rhs.Sunset)
 }
@@ -0,0 +1,207 @@
+package semantics
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func newColMatcherOrPanic(l ...string) ColMatcher {
+	cnf, err := GetConfig()
+	if err != nil {
+		panic("error getting Config")
+	}
+	m := ColMatcher{ColExprs{}}
+	for _, s := range l {
+		e, err := newColExpr(cnf, s)
+		if err != nil {
+			panic("error instantiating ColExpr")
+		}
+		m.ColExprs[e] = ObjExprAttr{}
+	}
+	return m
+}
+
+func newObjMatchersOrPanic(l ...string) ObjMatchers {
+	oms := ObjMatchers{}
+	for _, s := range l {
+		e := newObjExprOrPanic(s)
+		oms[e] = ObjMatcher{Include: e}
+	}
+	return oms
+}
+
+func TestSetEffectiveClassification(t *testing.T) {
+	classes := map[string]syntax.Class{
+		"public":       syntax.Class{Name: "public", Level: 1},
+		"internal":     syntax.Class{Name: "internal", Level: 2},
+		"confidential": syntax.Class{Name: "confidential", Level: 3},
+	}
+	parent := &InterfaceMetadata{Classification: 3, EffectiveClassification: 2}
+	tests := []struct {
+		imSyn   syntax.InterfaceMetadata
+		imSem   InterfaceMetadata
+		parent  *InterfaceMetadata
+		want    Classification
+		wantErr bool
+	}{
+		{
+			imSem: InterfaceMetadata{Classification: 3},
+			want:  3,
+		},
+		{
+			imSyn: syntax.InterfaceMetadata{MaskColumns: []string{"a.b.c.ssn"}, MaskedClassification: "internal"},
+			imSem: InterfaceMetadata{Classification: 3, MaskColumns: newColMatcherOrPanic("a.b.c.ssn")},
+			want:  2,
+		},
+		{
+			imSyn: syntax.InterfaceMetadata{HashColumns: []string{"a.b.c.ssn"}, MaskedClassification: "confidential"},
+			imSem: InterfaceMetadata{Classification: 3, HashColumns: newColMatcherOrPanic("a.b.c.ssn")},
+			want:  3,
+		},
+		{
+			imSem:  InterfaceMetadata{Classification: 3},
+			parent: parent,
+			want:   2, // inherits the effective classification of the parent
+		},
+		{
+			imSyn:  syntax.InterfaceMetadata{Classification: "confidential"},
+			imSem:  InterfaceMetadata{Classification: 3},
+			parent: parent,
+			want:   3, // own classification, so masking of the parent does not apply
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{MaskedClassification: "internal"},
+			imSem:   InterfaceMetadata{Classification: 3},
+			wantErr: true, // nothing masked or hashed
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{MaskColumns: []string{"a.b.c.ssn"}, MaskedClassification: "secret"},
+			imSem:   InterfaceMetadata{Classification: 3, MaskColumns: newColMatcherOrPanic("a.b.c.ssn")},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{MaskColumns: []string{"a.b.c.ssn"}, MaskedClassification: "confidential"},
+			imSem:   InterfaceMetadata{Classification: 2, MaskColumns: newColMatcherOrPanic("a.b.c.ssn")},
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		err := test.imSem.setEffectiveClassification(test.imSyn, test.parent, classes)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: setEffectiveClassification(%v): expected an error", i, test.imSyn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: setEffectiveClassification(%v): %v", i, test.imSyn, err)
+		} else if test.imSem.EffectiveClassification != test.want {
+			t.Errorf("test %d: setEffectiveClassification(%v): got %v, want %v", i, test.imSyn, test.imSem.EffectiveClassification, test.want)
+		}
+	}
+}
+
+func TestMaskedConsumptionOk(t *testing.T) {
+	masked := InterfaceMetadata{
+		ObjectMatchers: newObjMatchersOrPanic("a.b.*"),
+		Classification: 3,
+		MaskColumns:    newColMatcherOrPanic("a.b.*.ssn"),
+	}
+	unmasked := InterfaceMetadata{
+		ObjectMatchers: newObjMatchersOrPanic("a.b.c"),
+		Classification: 3,
+		MaskColumns:    newColMatcherOrPanic(),
+	}
+	elsewhere := InterfaceMetadata{
+		ObjectMatchers: newObjMatchersOrPanic("a.x.*"),
+		Classification: 3,
+		MaskColumns:    newColMatcherOrPanic(),
+	}
+	sameMasking := InterfaceMetadata{
+		ObjectMatchers: newObjMatchersOrPanic("a.b.c"),
+		Classification: 3,
+		MaskColumns:    newColMatcherOrPanic("a.b.*.ssn"),
+	}
+	g := Grupin{
+		Products: map[string]Product{
+			"src": Product{
+				ID: "src",
+				Interfaces: map[string]InterfaceMetadata{
+					"masked":       masked,
+					"unmasked":     unmasked,
+					"elsewhere":    elsewhere,
+					"same_masking": sameMasking,
+				},
+			},
+		},
+	}
+	iid := syntax.InterfaceID{ID: "masked", ProductID: "src"}
+	tests := []struct {
+		classification Classification
+		consumes       []string
+		want           bool
+	}{
+		{classification: 2, consumes: []string{"masked"}, want: true},
+		{classification: 2, consumes: []string{"masked", "unmasked"}, want: false},
+		{classification: 3, consumes: []string{"masked", "unmasked"}, want: true}, // does not rely on the masking
+		{classification: 2, consumes: []string{"masked", "elsewhere"}, want: true},
+		{classification: 2, consumes: []string{"masked", "same_masking"}, want: true},
+	}
+	for i, test := range tests {
+		p := Product{ID: "dst", Consumes: map[syntax.InterfaceID]map[string]string{}}
+		p.Classification = test.classification
+		for _, id := range test.consumes {
+			p.Consumes[syntax.InterfaceID{ID: id, ProductID: "src"}] = map[string]string{}
+		}
+		if got := g.maskedConsumptionOk(p, iid); got != test.want {
+			t.Errorf("test %d: maskedConsumptionOk(%v) = %v, want %v", i, test.consumes, got, test.want)
+		}
+	}
+}
+
+func TestSetHiddenDTAPs(t *testing.T) {
+	prd := "prd"
+	ds := DTAPSpec{Prod: &prd, NonProd: map[string]struct{}{"dev": {}, "sbx": {}}}
+	parent := &InterfaceMetadata{HiddenDTAPs: map[string]struct{}{"sbx": {}}}
+	tests := []struct {
+		hide    []string
+		parent  *InterfaceMetadata
+		want    []string
+		wantErr bool
+	}{
+		{want: []string{}},
+		{hide: []string{"sbx"}, want: []string{"sbx"}},
+		{parent: parent, want: []string{"sbx"}},
+		{hide: []string{"dev"}, parent: parent, want: []string{"dev", "sbx"}},
+		{hide: []string{"tst"}, wantErr: true},
+	}
+	for i, test := range tests {
+		imSem := InterfaceMetadata{}
+		err := imSem.setHiddenDTAPs(syntax.InterfaceMetadata{HideDTAPs: test.hide}, test.parent, ds)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: setHiddenDTAPs(%v): expected an error", i, test.hide)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: setHiddenDTAPs(%v): %v", i, test.hide, err)
+			continue
+		}
+		if len(imSem.HiddenDTAPs) != len(test.want) {
+			t.Errorf("test %d: setHiddenDTAPs(%v): got %v, want %v", i, test.hide, imSem.HiddenDTAPs, test.want)
+		}
+		for _, d := range test.want {
+			if !imSem.IsHidden(d) {
+				t.Errorf("test %d: setHiddenDTAPs(%v): dtap '%s' not hidden", i, test.hide, d)
+			}
+		}
+		if imSem.IsHidden(prd) {
+			t.Errorf("test %d: setHiddenDTAPs(%v): dtap '%s' hidden", i, test.hide, prd)
+		}
+	}
+	if len(parent.HiddenDTAPs) != 1 {
+		t.Errorf("setHiddenDTAPs modified the hidden dtaps of the parent: %v", parent.HiddenDTAPs)
+	}
+}
@@ -0,0 +1,126 @@
+package semantics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+type Lifecycle int
+
+const (
+	LifecycleActive Lifecycle = iota
+	LifecycleDeprecated
+	LifecycleRetired
+)
+
+func (l Lifecycle) String() string {
+	switch l {
+	case LifecycleDeprecated:
+		return "deprecated"
+	case LifecycleRetired:
+		return "retired"
+	}
+	return "active"
+}
+
+// setLifecycle sets the lifecycle of an interface. A deprecated interface can still be consumed by the products that
+// consume it already, until its sunset date; new consumers are refused, see CheckNewConsumption. A retired interface
+// can not be consumed at all.
+func (imSem *InterfaceMetadata) setLifecycle(iSyn syntax.Interface) error {
+	switch iSyn.Lifecycle {
+	case "", "active":
+		imSem.Lifecycle = LifecycleActive
+	case "deprecated":
+		imSem.Lifecycle = LifecycleDeprecated
+	case "retired":
+		imSem.Lifecycle = LifecycleRetired
+	default:
+		return fmt.Errorf("lifecycle: should be one of active, deprecated, retired")
+	}
+	if imSem.Lifecycle != LifecycleDeprecated {
+		if iSyn.SunsetDate != "" {
+			return fmt.Errorf("sunset_date: only for deprecated interfaces")
+		}
+		return nil
+	}
+	if iSyn.SunsetDate == "" {
+		return fmt.Errorf("sunset_date: required for deprecated interfaces")
+	}
+	t, err := time.Parse(time.DateOnly, iSyn.SunsetDate)
+	if err != nil {
+		return fmt.Errorf("invalid sunset_date '%s', use a date like 2006-01-02", iSyn.SunsetDate)
+	}
+	imSem.Sunset = t.AddDate(0, 0, 1)
+	return nil
+}
+
+// IsSunset returns whether the interface may no longer be consumed at time t, because it is deprecated and its
+// sunset date has passed, or because it is retired
+func (imSem InterfaceMetadata) IsSunset(t time.Time) bool {
+	return imSem.Lifecycle == LifecycleRetired || imSem.Lifecycle == LifecycleDeprecated && !t.Before(imSem.Sunset)
+}
+
+// SunsetDate returns the last day a deprecated interface can be consumed, for display
+func (imSem InterfaceMetadata) SunsetDate() string {
+	if imSem.Sunset.IsZero() {
+		return ""
+	}
+	return imSem.Sunset.AddDate(0, 0, -1).Format(time.DateOnly)
+}
+
+type DeprecatedConsumption struct {
+	syntax.InterfaceID
+	ConsumerID string
+	SunsetDate string
+}
+
+// DeprecatedConsumptions returns which products consume deprecated interfaces, soonest sunset first
+func (g Grupin) DeprecatedConsumptions() []DeprecatedConsumption {
+	r := []DeprecatedConsumption{}
+	for _, p := range g.Products {
+		for iid := range p.Consumes {
+			if i := g.Products[iid.ProductID].Interfaces[iid.ID]; i.Lifecycle == LifecycleDeprecated {
+				r = append(r, DeprecatedConsumption{InterfaceID: iid, ConsumerID: p.ID, SunsetDate: i.SunsetDate()})
+			}
+		}
+	}
+	slices.SortFunc(r, func(a, b DeprecatedConsumption) int {
+		if c := strings.Compare(a.SunsetDate, b.SunsetDate); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.ProductID, b.ProductID); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.ID, b.ID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ConsumerID, b.ConsumerID)
+	})
+	return r
+}
+
+// CheckNewConsumption refuses consumption of deprecated interfaces by products that did not consume them in an
+// older version of the YAML; the old YAML is only parsed, it need not be valid with the current semantics
+func (g Grupin) CheckNewConsumption(old syntax.Grupin) error {
+	oldConsumes := map[ConsumptionEdge]struct{}{}
+	for _, p := range old.Products {
+		for _, cs := range p.Consumes {
+			oldConsumes[ConsumptionEdge{InterfaceID: cs.InterfaceID, ConsumerID: p.ID}] = struct{}{}
+		}
+	}
+	for _, p := range g.Products {
+		for iid := range p.Consumes {
+			if g.Products[iid.ProductID].Interfaces[iid.ID].Lifecycle != LifecycleDeprecated {
+				continue
+			}
+			if _, ok := oldConsumes[ConsumptionEdge{InterfaceID: iid, ConsumerID: p.ID}]; !ok {
+				return &PolicyError{s: fmt.Sprintf("product '%s': consumes interface '%s', which is deprecated", p.ID, iid)}
+			}
+		}
+	}
+	return nil
+}
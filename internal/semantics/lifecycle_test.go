@@ -0,0 +1,145 @@
+package semantics
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func TestSetLifecycle(t *testing.T) {
+	tests := []struct {
+		iSyn       syntax.Interface
+		want       Lifecycle
+		sunsetDate string
+		wantErr    bool
+	}{
+		{
+			iSyn: syntax.Interface{},
+			want: LifecycleActive,
+		},
+		{
+			iSyn:       syntax.Interface{Lifecycle: "deprecated", SunsetDate: "2025-12-31"},
+			want:       LifecycleDeprecated,
+			sunsetDate: "2025-12-31",
+		},
+		{
+			iSyn: syntax.Interface{Lifecycle: "retired"},
+			want: LifecycleRetired,
+		},
+		{
+			iSyn:    syntax.Interface{Lifecycle: "deprecated"},
+			wantErr: true,
+		},
+		{
+			iSyn:    syntax.Interface{Lifecycle: "deprecated", SunsetDate: "31-12-2025"},
+			wantErr: true,
+		},
+		{
+			iSyn:    syntax.Interface{Lifecycle: "active", SunsetDate: "2025-12-31"},
+			wantErr: true,
+		},
+		{
+			iSyn:    syntax.Interface{Lifecycle: "obsolete"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		imSem := InterfaceMetadata{}
+		err := imSem.setLifecycle(test.iSyn)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("setLifecycle(%v): expected an error", test.iSyn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("setLifecycle(%v): %v", test.iSyn, err)
+			continue
+		}
+		if imSem.Lifecycle != test.want {
+			t.Errorf("setLifecycle(%v): got %v, want %v", test.iSyn, imSem.Lifecycle, test.want)
+		}
+		if got := imSem.SunsetDate(); got != test.sunsetDate {
+			t.Errorf("setLifecycle(%v): SunsetDate() = %q, want %q", test.iSyn, got, test.sunsetDate)
+		}
+	}
+}
+
+func TestIsSunset(t *testing.T) {
+	deprecated := InterfaceMetadata{}
+	if err := deprecated.setLifecycle(syntax.Interface{Lifecycle: "deprecated", SunsetDate: "2025-12-31"}); err != nil {
+		t.Fatalf("setLifecycle: %v", err)
+	}
+	tests := []struct {
+		imSem InterfaceMetadata
+		t     string
+		want  bool
+	}{
+		{imSem: InterfaceMetadata{}, t: "2025-12-31", want: false},
+		{imSem: deprecated, t: "2025-12-31", want: false},
+		{imSem: deprecated, t: "2026-01-01", want: true},
+		{imSem: InterfaceMetadata{Lifecycle: LifecycleRetired}, t: "2025-01-01", want: true},
+	}
+	for _, test := range tests {
+		if got := test.imSem.IsSunset(date(test.t)); got != test.want {
+			t.Errorf("IsSunset(%s) with lifecycle %v = %v, want %v", test.t, test.imSem.Lifecycle, got, test.want)
+		}
+	}
+}
+
+func TestCheckNewConsumption(t *testing.T) {
+	active := syntax.InterfaceID{ID: "active", ProductID: "src"}
+	deprecated := syntax.InterfaceID{ID: "deprecated", ProductID: "src"}
+	g := Grupin{
+		Products: map[string]Product{
+			"src": Product{
+				ID: "src",
+				Interfaces: map[string]InterfaceMetadata{
+					"active":     InterfaceMetadata{},
+					"deprecated": InterfaceMetadata{Lifecycle: LifecycleDeprecated},
+				},
+			},
+			"dst": Product{
+				ID: "dst",
+				Consumes: map[syntax.InterfaceID]map[string]string{
+					active:     map[string]string{},
+					deprecated: map[string]string{},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		old     syntax.Grupin
+		wantErr bool
+	}{
+		{
+			old: syntax.Grupin{Products: map[string]syntax.Product{
+				"dst": syntax.Product{ID: "dst", Consumes: []syntax.ConsumptionSpec{{InterfaceID: deprecated}}},
+			}},
+		},
+		{
+			old: syntax.Grupin{Products: map[string]syntax.Product{
+				"dst": syntax.Product{ID: "dst", Consumes: []syntax.ConsumptionSpec{{InterfaceID: active}}},
+			}},
+			wantErr: true,
+		},
+		{
+			old: syntax.Grupin{Products: map[string]syntax.Product{
+				"other": syntax.Product{ID: "other", Consumes: []syntax.ConsumptionSpec{{InterfaceID: deprecated}}},
+			}},
+			wantErr: true, // another product consumed it
+		},
+		{
+			old:     syntax.Grupin{},
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		err := g.CheckNewConsumption(test.old)
+		if test.wantErr && err == nil {
+			t.Errorf("test %d: CheckNewConsumption: expected an error", i)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("test %d: CheckNewConsumption: %v", i, err)
+		}
+	}
+}
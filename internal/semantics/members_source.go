@@ -0,0 +1,142 @@
+package semantics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+// scimGroup holds the fields we need of a SCIM group resource, see RFC 7643
+type scimGroup struct {
+	DisplayName string `json:"displayName"`
+	Members     []struct {
+		Value string `json:"value"` // the id of the member in the identity provider
+	} `json:"members"`
+}
+
+// scimListResponse is what a SCIM service returns when listing groups, see RFC 7644
+type scimListResponse struct {
+	Resources []scimGroup `json:"Resources"`
+}
+
+func openMembersSource(src syntax.MembersSource) (io.ReadCloser, error) {
+	if src.Path != "" {
+		return os.Open(src.Path)
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(src.URL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", src.URL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func readUserNamesCSV(r io.Reader, column string) ([]string, error) {
+	if column == "" {
+		column = "user_name"
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header found")
+	}
+	idx := slices.Index(records[0], column)
+	if idx == -1 {
+		return nil, fmt.Errorf("column '%s' not found", column)
+	}
+	names := []string{}
+	for _, rec := range records[1:] {
+		names = append(names, rec[idx])
+	}
+	return names, nil
+}
+
+// readUserNamesSCIM returns the ids of the members of a SCIM group
+func readUserNamesSCIM(r io.Reader, group string) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// The export is either a single group, or a list response with groups
+	var l scimListResponse
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	if l.Resources == nil {
+		var g scimGroup
+		if err := json.Unmarshal(b, &g); err != nil {
+			return nil, err
+		}
+		l.Resources = []scimGroup{g}
+	}
+	var found *scimGroup
+	for i, g := range l.Resources {
+		if group == "" && len(l.Resources) > 1 {
+			return nil, fmt.Errorf("export has more than one group, specify which one")
+		}
+		if group == "" || g.DisplayName == group {
+			found = &l.Resources[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("group '%s' not found", group)
+	}
+	// We do not use display, which holds a human readable name, like Alice Smith, not a user name
+	ids := []string{}
+	for _, m := range found.Members {
+		ids = append(ids, m.Value)
+	}
+	return ids, nil
+}
+
+// loadMembers reads the user names from a members source, and maps them on Snowflake users; user names that are not
+// in the user mapping are taken to be Snowflake users as they are. SCIM groups hold the ids of their members in the
+// identity provider, rather than user names, so these should all be in the user mapping.
+func loadMembers(cnf *Config, src syntax.MembersSource) (map[Ident]struct{}, error) {
+	f, err := openMembersSource(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	switch src.Format {
+	case "csv":
+		names, err = readUserNamesCSV(f, src.Column)
+	case "json":
+		// a list of user names
+		err = json.NewDecoder(f).Decode(&names)
+	case "scim":
+		names, err = readUserNamesSCIM(f, src.Group)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s export: %w", src.Format, err)
+	}
+	members := map[Ident]struct{}{}
+	for _, name := range names {
+		if user, ok := src.UserMapping[name]; ok {
+			name = user
+		} else if src.Format == "scim" {
+			return nil, fmt.Errorf("member '%s' of scim group: not found in user_mapping", name)
+		}
+		ident, err := NewIdentStripQuotesIfAny(name, cnf.ValidQuotedExpr, cnf.ValidUnquotedExpr)
+		if err != nil {
+			return nil, err
+		}
+		members[ident] = struct{}{}
+	}
+	return members, nil
+}
@@ -0,0 +1,125 @@
+package semantics
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadUserNamesCSV(t *testing.T) {
+	tests := []struct {
+		csv     string
+		column  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			csv:  "user_name,email\nalice,alice@example.com\nbob,bob@example.com\n",
+			want: []string{"alice", "bob"},
+		},
+		{
+			csv:    "user_name,email\nalice,alice@example.com\n",
+			column: "email",
+			want:   []string{"alice@example.com"},
+		},
+		{
+			csv:  "user_name\n",
+			want: []string{},
+		},
+		{
+			csv:     "name,email\nalice,alice@example.com\n",
+			wantErr: true,
+		},
+		{
+			csv:     "",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		got, err := readUserNamesCSV(strings.NewReader(test.csv), test.column)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("readUserNamesCSV(%q, %q): expected an error", test.csv, test.column)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readUserNamesCSV(%q, %q): %v", test.csv, test.column, err)
+		} else if !slices.Equal(got, test.want) {
+			t.Errorf("readUserNamesCSV(%q, %q) = %v, want %v", test.csv, test.column, got, test.want)
+		}
+	}
+}
+
+func TestReadUserNamesSCIM(t *testing.T) {
+	group := `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+  "id": "e9e30dba-f08f-4109-8486-d5c6a331660a",
+  "displayName": "Crew",
+  "members": [
+    {"value": "2819c223", "display": "Alice Smith"},
+    {"value": "902c246b", "display": "Bob Jones"}
+  ]
+}`
+	list := `{
+  "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+  "totalResults": 2,
+  "Resources": [
+    {"displayName": "Crew", "members": [{"value": "2819c223", "display": "Alice Smith"}]},
+    {"displayName": "Other", "members": [{"value": "902c246b", "display": "Bob Jones"}]}
+  ]
+}`
+	tests := []struct {
+		json    string
+		group   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			json: group,
+			want: []string{"2819c223", "902c246b"},
+		},
+		{
+			json:  group,
+			group: "Crew",
+			want:  []string{"2819c223", "902c246b"},
+		},
+		{
+			json:    group,
+			group:   "Other",
+			wantErr: true,
+		},
+		{
+			json:  list,
+			group: "Other",
+			want:  []string{"902c246b"},
+		},
+		{
+			json:    list,
+			wantErr: true, // more than one group
+		},
+		{
+			json:    list,
+			group:   "Nobody",
+			wantErr: true,
+		},
+		{
+			json:    "[]",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		got, err := readUserNamesSCIM(strings.NewReader(test.json), test.group)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("readUserNamesSCIM(%q, %q): expected an error", test.json, test.group)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readUserNamesSCIM(%q, %q): %v", test.json, test.group, err)
+		} else if !slices.Equal(got, test.want) {
+			t.Errorf("readUserNamesSCIM(%q, %q) = %v, want %v", test.json, test.group, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package semantics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+// A Membership of a team; a zero From or Until means the membership is not bounded on that side. Until is exclusive,
+// it is the start of the day after the last day of the membership, UTC.
+type Membership struct {
+	From   time.Time
+	Until  time.Time
+	Reason string
+}
+
+func newMembership(m syntax.Member) (Membership, error) {
+	r := Membership{Reason: m.Reason}
+	if m.From != "" {
+		t, err := time.Parse(time.DateOnly, m.From)
+		if err != nil {
+			return r, fmt.Errorf("invalid from '%s', use a date like 2006-01-02", m.From)
+		}
+		r.From = t
+	}
+	if m.Until != "" {
+		t, err := time.Parse(time.DateOnly, m.Until)
+		if err != nil {
+			return r, fmt.Errorf("invalid until '%s', use a date like 2006-01-02", m.Until)
+		}
+		r.Until = t.AddDate(0, 0, 1)
+	}
+	if !r.From.IsZero() && !r.Until.IsZero() && !r.From.Before(r.Until) {
+		return r, fmt.Errorf("from should not be after until")
+	}
+	return r, nil
+}
+
+func (m Membership) IsActive(t time.Time) bool {
+	return (m.From.IsZero() || !t.Before(m.From)) && (m.Until.IsZero() || t.Before(m.Until))
+}
+
+// LastDay returns the last day of the membership, for display
+func (m Membership) LastDay() string {
+	if m.Until.IsZero() {
+		return ""
+	}
+	return m.Until.AddDate(0, 0, -1).Format(time.DateOnly)
+}
+
+func (lhs Membership) Equal(rhs Membership) bool {
+	return lhs.From.Equal(rhs.From) && lhs.Until.Equal(rhs.Until) && lhs.Reason == rhs.Reason
+}
+
+type ExpiringMembership struct {
+	TeamID string
+	Member Ident
+	Membership
+}
+
+// ExpiringMemberships returns the memberships of teams that are active at time t, and that end within the given
+// number of days, soonest first
+func (g Grupin) ExpiringMemberships(t time.Time, days int) []ExpiringMembership {
+	r := []ExpiringMembership{}
+	end := t.AddDate(0, 0, days)
+	for teamID, team := range g.Teams {
+		for ident, m := range team.Members {
+			if m.IsActive(t) && !m.Until.IsZero() && !m.Until.After(end) {
+				r = append(r, ExpiringMembership{TeamID: teamID, Member: ident, Membership: m})
+			}
+		}
+	}
+	slices.SortFunc(r, func(a, b ExpiringMembership) int {
+		if c := a.Until.Compare(b.Until); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Member), string(b.Member))
+	})
+	return r
+}
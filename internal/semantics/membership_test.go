@@ -0,0 +1,123 @@
+package semantics
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic("error parsing date")
+	}
+	return t
+}
+
+func TestMembershipIsActive(t *testing.T) {
+	tests := []struct {
+		member  syntax.Member
+		t       time.Time
+		want    bool
+		lastDay string
+		wantErr bool
+	}{
+		{
+			member: syntax.Member{ID: "a"},
+			t:      date("2025-06-01"),
+			want:   true,
+		},
+		{
+			member: syntax.Member{ID: "a", From: "2025-06-01"},
+			t:      date("2025-05-31"),
+			want:   false,
+		},
+		{
+			member: syntax.Member{ID: "a", From: "2025-06-01"},
+			t:      date("2025-06-01"),
+			want:   true,
+		},
+		{
+			member:  syntax.Member{ID: "a", Until: "2025-06-30"},
+			t:       date("2025-06-30").Add(23 * time.Hour),
+			want:    true,
+			lastDay: "2025-06-30",
+		},
+		{
+			member:  syntax.Member{ID: "a", Until: "2025-06-30"},
+			t:       date("2025-07-01"),
+			want:    false,
+			lastDay: "2025-06-30",
+		},
+		{
+			member:  syntax.Member{ID: "a", From: "2025-06-01", Until: "2025-06-01"},
+			t:       date("2025-06-01"),
+			want:    true,
+			lastDay: "2025-06-01",
+		},
+		{
+			member:  syntax.Member{ID: "a", From: "2025-06-02", Until: "2025-06-01"},
+			wantErr: true,
+		},
+		{
+			member:  syntax.Member{ID: "a", From: "01-06-2025"},
+			wantErr: true,
+		},
+		{
+			member:  syntax.Member{ID: "a", Until: "2025-06-31"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		m, err := newMembership(test.member)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("newMembership(%v): expected an error", test.member)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newMembership(%v): %v", test.member, err)
+			continue
+		}
+		if got := m.IsActive(test.t); got != test.want {
+			t.Errorf("newMembership(%v).IsActive(%v) = %v, want %v", test.member, test.t, got, test.want)
+		}
+		if got := m.LastDay(); got != test.lastDay {
+			t.Errorf("newMembership(%v).LastDay() = %q, want %q", test.member, got, test.lastDay)
+		}
+	}
+}
+
+func TestExpiringMemberships(t *testing.T) {
+	g := Grupin{
+		Teams: map[string]Team{
+			"t1": Team{
+				ID: "t1",
+				Members: map[Ident]Membership{
+					"A": Membership{},                                                    // does not expire
+					"B": Membership{Until: date("2025-06-11")},                           // expires within 10 days
+					"C": Membership{Until: date("2025-06-05")},                           // expires sooner
+					"D": Membership{Until: date("2025-07-01")},                           // expires later
+					"E": Membership{Until: date("2025-05-31")},                           // expired
+					"F": Membership{From: date("2025-06-03"), Until: date("2025-06-05")}, // not active yet
+				},
+			},
+			"t2": Team{
+				ID: "t2",
+				Members: map[Ident]Membership{
+					"A": Membership{Until: date("2025-06-05")},
+				},
+			},
+		},
+	}
+	got := []Ident{}
+	for _, m := range g.ExpiringMemberships(date("2025-06-01"), 10) {
+		got = append(got, m.Member)
+	}
+	if want := []Ident{"A", "C", "B"}; !slices.Equal(got, want) {
+		t.Errorf("ExpiringMemberships() = %v, want %v", got, want)
+	}
+}
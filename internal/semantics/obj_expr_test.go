@@ -5,7 +5,11 @@ import (
 )
 
 func newObjExprOrPanic(s string) ObjExpr {
-	if o, err := newObjExpr(s); err == nil {
+	cnf, err := GetConfig()
+	if err != nil {
+		panic("error getting Config")
+	}
+	if o, err := newObjExpr(cnf, s); err == nil {
 		return o
 	}
 	panic("error instantiating ObjExpr")
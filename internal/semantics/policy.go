@@ -0,0 +1,132 @@
+package semantics
+
+import (
+	"fmt"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+type PolicyLevel int
+
+const (
+	PolicyOff PolicyLevel = iota
+	PolicyWarn
+	PolicyErr
+)
+
+func newPolicyLevel(s string) PolicyLevel {
+	switch s {
+	case "error":
+		return PolicyErr
+	case "warn":
+		return PolicyWarn
+	}
+	return PolicyOff
+}
+
+// Rules that can be configured with policy elements in the YAML
+const (
+	RuleClassificationOrdering = "classification_ordering"  // products may not consume interfaces with a higher classification
+	RuleProdConsumesProd       = "prod_consumes_prod"       // production may not consume non-production
+	RuleUserGroupCompatibility = "user_group_compatibility" // products may only consume interfaces with user groups they have themselves
+	RuleMaxProductsPerTeam     = "max_products_per_team"    // teams may work on at most max products
+	RuleMaxTeamsPerProduct     = "max_teams_per_product"    // at most max teams may work on a product
+	RuleConsumptionApproval    = "consumption_approval"     // consumption requires approval of the producing product; scoped by the producer
+)
+
+// A Policy is how strictly a rule is enforced; a rule that limits a number also has a maximum
+type Policy struct {
+	Rule  string
+	Level PolicyLevel
+	Max   int
+}
+
+var defaultPolicies = map[string]Policy{
+	RuleClassificationOrdering: Policy{Rule: RuleClassificationOrdering, Level: PolicyErr},
+	RuleProdConsumesProd:       Policy{Rule: RuleProdConsumesProd, Level: PolicyErr},
+	RuleUserGroupCompatibility: Policy{Rule: RuleUserGroupCompatibility, Level: PolicyOff},
+	RuleMaxProductsPerTeam:     Policy{Rule: RuleMaxProductsPerTeam, Level: PolicyOff},
+	RuleMaxTeamsPerProduct:     Policy{Rule: RuleMaxTeamsPerProduct, Level: PolicyOff},
+	RuleConsumptionApproval:    Policy{Rule: RuleConsumptionApproval, Level: PolicyOff},
+}
+
+func hasMax(rule string) bool {
+	return rule == RuleMaxProductsPerTeam || rule == RuleMaxTeamsPerProduct
+}
+
+// Policies holds the policy of every rule, for all products, and, where scoped, for particular products
+type Policies struct {
+	All        map[string]Policy
+	ByProduct  map[string]map[string]Policy
+	Violations []*PolicyError // rules at level warn that were violated
+}
+
+func newPolicies(policiesSyn []syntax.Policy, products map[string]Product) (Policies, error) {
+	ps := Policies{
+		All:       map[string]Policy{},
+		ByProduct: map[string]map[string]Policy{},
+	}
+	for k, v := range defaultPolicies {
+		ps.All[k] = v
+	}
+	seenAll := map[string]bool{}
+	for _, pSyn := range policiesSyn {
+		if _, ok := defaultPolicies[pSyn.Rule]; !ok {
+			return ps, &SetLogicError{fmt.Sprintf("policy: unknown rule '%s'", pSyn.Rule)}
+		}
+		p := Policy{Rule: pSyn.Rule, Level: newPolicyLevel(pSyn.Level), Max: pSyn.Max}
+		if hasMax(p.Rule) && p.Level != PolicyOff && p.Max == 0 {
+			return ps, fmt.Errorf("policy '%s': max is required", p.Rule)
+		}
+		if !hasMax(p.Rule) && p.Max != 0 {
+			return ps, fmt.Errorf("policy '%s': max is not supported by this rule", p.Rule)
+		}
+		if len(pSyn.Products) == 0 {
+			if seenAll[p.Rule] {
+				return ps, fmt.Errorf("policy '%s': specified more than once for all products", p.Rule)
+			}
+			seenAll[p.Rule] = true
+			ps.All[p.Rule] = p
+			continue
+		}
+		if p.Rule == RuleMaxProductsPerTeam {
+			return ps, fmt.Errorf("policy '%s': can not be scoped to products", p.Rule)
+		}
+		for _, pID := range pSyn.Products {
+			if _, ok := products[pID]; !ok {
+				return ps, &SetLogicError{fmt.Sprintf("policy '%s': unknown product id '%s'", p.Rule, pID)}
+			}
+			if _, ok := ps.ByProduct[pID]; !ok {
+				ps.ByProduct[pID] = map[string]Policy{}
+			}
+			if _, ok := ps.ByProduct[pID][p.Rule]; ok {
+				return ps, fmt.Errorf("policy '%s': specified more than once for product '%s'", p.Rule, pID)
+			}
+			ps.ByProduct[pID][p.Rule] = p
+		}
+	}
+	return ps, nil
+}
+
+// get returns the policy of a rule for a product; an empty product id gets the policy for all products
+func (ps Policies) get(rule string, productID string) Policy {
+	if p, ok := ps.ByProduct[productID][rule]; ok {
+		return p
+	}
+	return ps.All[rule]
+}
+
+// check returns a PolicyError if the rule is violated, and enforced with level error; if it is enforced with level
+// warn, the violation is kept, so that it can be reported
+func (ps *Policies) check(rule string, productID string, ok bool, format string, a ...any) error {
+	if ok {
+		return nil
+	}
+	switch ps.get(rule, productID).Level {
+	case PolicyErr:
+		return &PolicyError{RuleID: rule, s: fmt.Sprintf(format, a...)}
+	case PolicyWarn:
+		ps.Violations = append(ps.Violations, &PolicyError{RuleID: rule, s: fmt.Sprintf(format, a...)})
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package semantics
 
 type PolicyError struct {
-	s string
+	RuleID string // empty for rules that can not be configured
+	s      string
 }
 
 func (e *PolicyError) Error() string {
+	if e.RuleID != "" {
+		return e.RuleID + ": " + e.s
+	}
 	return e.s
 }
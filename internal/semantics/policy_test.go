@@ -0,0 +1,142 @@
+package semantics
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func TestNewPolicies(t *testing.T) {
+	products := map[string]Product{"a": Product{ID: "a"}, "b": Product{ID: "b"}}
+	tests := []struct {
+		policies []syntax.Policy
+		rule     string
+		product  string
+		want     Policy
+		wantErr  bool
+	}{
+		{
+			rule: RuleClassificationOrdering,
+			want: Policy{Rule: RuleClassificationOrdering, Level: PolicyErr},
+		},
+		{
+			rule: RuleUserGroupCompatibility,
+			want: Policy{Rule: RuleUserGroupCompatibility, Level: PolicyOff},
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleClassificationOrdering, Level: "warn"}},
+			rule:     RuleClassificationOrdering,
+			product:  "a",
+			want:     Policy{Rule: RuleClassificationOrdering, Level: PolicyWarn},
+		},
+		{
+			policies: []syntax.Policy{
+				{Rule: RuleClassificationOrdering, Level: "warn"},
+				{Rule: RuleClassificationOrdering, Level: "off", Products: []string{"a"}},
+			},
+			rule:    RuleClassificationOrdering,
+			product: "a",
+			want:    Policy{Rule: RuleClassificationOrdering, Level: PolicyOff},
+		},
+		{
+			policies: []syntax.Policy{
+				{Rule: RuleClassificationOrdering, Level: "warn"},
+				{Rule: RuleClassificationOrdering, Level: "off", Products: []string{"a"}},
+			},
+			rule:    RuleClassificationOrdering,
+			product: "b",
+			want:    Policy{Rule: RuleClassificationOrdering, Level: PolicyWarn},
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleMaxTeamsPerProduct, Level: "error", Max: 3}},
+			rule:     RuleMaxTeamsPerProduct,
+			want:     Policy{Rule: RuleMaxTeamsPerProduct, Level: PolicyErr, Max: 3},
+		},
+		{
+			policies: []syntax.Policy{{Rule: "no_such_rule", Level: "error"}},
+			wantErr:  true,
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleMaxTeamsPerProduct, Level: "error"}}, // max is required
+			wantErr:  true,
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleProdConsumesProd, Level: "error", Max: 3}},
+			wantErr:  true,
+		},
+		{
+			policies: []syntax.Policy{
+				{Rule: RuleProdConsumesProd, Level: "error"},
+				{Rule: RuleProdConsumesProd, Level: "warn"},
+			},
+			wantErr: true,
+		},
+		{
+			policies: []syntax.Policy{
+				{Rule: RuleProdConsumesProd, Level: "error", Products: []string{"a"}},
+				{Rule: RuleProdConsumesProd, Level: "warn", Products: []string{"b", "a"}},
+			},
+			wantErr: true,
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleProdConsumesProd, Level: "error", Products: []string{"c"}}},
+			wantErr:  true,
+		},
+		{
+			policies: []syntax.Policy{{Rule: RuleMaxProductsPerTeam, Level: "error", Max: 2, Products: []string{"a"}}},
+			wantErr:  true,
+		},
+	}
+	for i, test := range tests {
+		ps, err := newPolicies(test.policies, products)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: newPolicies(%v): expected an error", i, test.policies)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: newPolicies(%v): %v", i, test.policies, err)
+		} else if got := ps.get(test.rule, test.product); got != test.want {
+			t.Errorf("test %d: get(%q, %q) = %v, want %v", i, test.rule, test.product, got, test.want)
+		}
+	}
+}
+
+func TestPoliciesCheck(t *testing.T) {
+	products := map[string]Product{"a": Product{ID: "a"}}
+	ps, err := newPolicies([]syntax.Policy{
+		{Rule: RuleClassificationOrdering, Level: "warn"},
+		{Rule: RuleClassificationOrdering, Level: "error", Products: []string{"a"}},
+		{Rule: RuleProdConsumesProd, Level: "off"},
+	}, products)
+	if err != nil {
+		t.Fatalf("newPolicies: %v", err)
+	}
+	tests := []struct {
+		rule           string
+		product        string
+		ok             bool
+		wantErr        bool
+		wantViolations int
+	}{
+		{rule: RuleClassificationOrdering, product: "a", ok: true},
+		{rule: RuleClassificationOrdering, product: "a", ok: false, wantErr: true},
+		{rule: RuleClassificationOrdering, product: "b", ok: false, wantViolations: 1},
+		{rule: RuleProdConsumesProd, product: "a", ok: false},
+	}
+	for i, test := range tests {
+		ps.Violations = nil
+		err := ps.check(test.rule, test.product, test.ok, "violated")
+		if test.wantErr {
+			if pe, ok := err.(*PolicyError); !ok || pe.RuleID != test.rule {
+				t.Errorf("test %d: check(%q, %q, %v): expected a PolicyError for the rule, got %v", i, test.rule, test.product, test.ok, err)
+			}
+		} else if err != nil {
+			t.Errorf("test %d: check(%q, %q, %v): %v", i, test.rule, test.product, test.ok, err)
+		}
+		if len(ps.Violations) != test.wantViolations {
+			t.Errorf("test %d: check(%q, %q, %v): %d violations, want %d", i, test.rule, test.product, test.ok, len(ps.Violations), test.wantViolations)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/syntax"
 )
@@ -18,6 +19,7 @@ type Product struct {
 	UserGroupColumn   ColMatcher
 	Interfaces        map[string]InterfaceMetadata
 	BlockCentralTeams bool
+	ConfiscateAfter   *time.Duration // nil if not set; zero means never
 }
 
 func newProduct(cnf *Config, pSyn syntax.Product, classes map[string]syntax.Class, globalUserGroups map[string]bool,
@@ -27,6 +29,10 @@ func newProduct(cnf *Config, pSyn syntax.Product, classes map[string]syntax.Clas
 		Interfaces:        map[string]InterfaceMetadata{},
 		BlockCentralTeams: pSyn.BlockCentralTeams,
 	}
+	if pSyn.ConfiscateAfter != nil {
+		d := time.Duration(*pSyn.ConfiscateAfter) * 24 * time.Hour
+		pSem.ConfiscateAfter = &d
+	}
 
 	if _, err := NewID(cnf, pSyn.ID); err != nil {
 		return pSem, err
@@ -49,14 +55,14 @@ func newProduct(cnf *Config, pSyn syntax.Product, classes map[string]syntax.Clas
 		}
 		if cs.ProductID == pSem.ID {
 			return pSem, &PolicyError{
-				fmt.Sprintf("product '%s' not allowed to consume own interface '%s'", cs.ProductID, cs.ID),
+				s: fmt.Sprintf("product '%s' not allowed to consume own interface '%s'", cs.ProductID, cs.ID),
 			}
 		}
 		pSem.Consumes[cs.InterfaceID] = map[string]string{} // dtap mapping
 		for dtap, isProd := range pSem.DTAPs.All() {
 			if !slices.Contains(cs.NonConsumingDTAPs, dtap) {
 				if isProd {
-					pSem.Consumes[cs.InterfaceID][dtap] = "" // will be set later in grupin.AllConsumedOK
+					pSem.Consumes[cs.InterfaceID][dtap] = cs.DTAPMapping[dtap] // if empty, will be set later in grupin.allConsumedOk
 				} else if sourceDTAP, ok := cs.DTAPMapping[dtap]; ok {
 					pSem.Consumes[cs.InterfaceID][dtap] = sourceDTAP
 				} else {
@@ -105,10 +111,10 @@ func newProduct(cnf *Config, pSyn syntax.Product, classes map[string]syntax.Clas
 	// Some last sanity checks
 	if len(pSem.UserGroups) == 0 {
 		if pSem.UserGroupMappingID != "" {
-			return pSem, fmt.Errorf("product '%s': no usergroups, but user_group_mapping_id specified")
+			return pSem, fmt.Errorf("product '%s': no usergroups, but user_group_mapping_id specified", pSem.ID)
 		}
 		if len(pSem.UserGroupRenderings) > 0 {
-			return pSem, fmt.Errorf("product '%s': no usergroups, but user_group_renderings specified")
+			return pSem, fmt.Errorf("product '%s': no usergroups, but user_group_renderings specified", pSem.ID)
 		}
 	}
 
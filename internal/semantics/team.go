@@ -2,25 +2,29 @@ package semantics
 
 import (
 	"fmt"
+	"iter"
 	"maps"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/syntax"
 )
 
 type Team struct {
 	ID          string
-	Members     map[Ident]struct{}
+	Members     map[Ident]Membership
 	WorkOn      map[string]struct{}
 	IsCentral   bool
 	OnlyNonProd bool
+	OnCallFor   map[string]struct{}
 }
 
 func newTeam(cnf *Config, teamSyn syntax.Team, products map[string]Product) (Team, error) {
 	team := Team{
-		Members:     map[Ident]struct{}{},
+		Members:     map[Ident]Membership{},
 		WorkOn:      map[string]struct{}{},
 		IsCentral:   teamSyn.IsCentral,
 		OnlyNonProd: teamSyn.OnlyNonProd,
+		OnCallFor:   map[string]struct{}{},
 	}
 	// Set ID
 	if _, err := NewID(cnf, teamSyn.ID); err != nil {
@@ -31,14 +35,32 @@ func newTeam(cnf *Config, teamSyn syntax.Team, products map[string]Product) (Tea
 
 	// Set Members
 	for _, m := range teamSyn.Members {
-		ident, err := NewIdentStripQuotesIfAny(m, cnf.ValidQuotedExpr, cnf.ValidUnquotedExpr)
+		ident, err := NewIdentStripQuotesIfAny(m.ID, cnf.ValidQuotedExpr, cnf.ValidUnquotedExpr)
 		if err != nil {
 			return team, err
 		}
 		if _, ok := team.Members[ident]; ok {
 			return team, fmt.Errorf("members: duplicate identifier '%v'", ident)
 		}
-		team.Members[ident] = struct{}{}
+		membership, err := newMembership(m)
+		if err != nil {
+			return team, fmt.Errorf("members: '%v': %w", ident, err)
+		}
+		team.Members[ident] = membership
+	}
+
+	// Add members from an external source, if any; members listed explicitly take precedence, so that their
+	// membership can be bounded in time
+	if teamSyn.MembersFrom != nil {
+		members, err := loadMembers(cnf, *teamSyn.MembersFrom)
+		if err != nil {
+			return team, fmt.Errorf("team '%s', members_from: %w", team.ID, err)
+		}
+		for ident := range members {
+			if _, ok := team.Members[ident]; !ok {
+				team.Members[ident] = Membership{}
+			}
+		}
 	}
 
 	// Set WorkOn
@@ -52,6 +74,17 @@ func newTeam(cnf *Config, teamSyn syntax.Team, products map[string]Product) (Tea
 		team.WorkOn[pID] = struct{}{}
 	}
 
+	// Set OnCallFor
+	for _, pID := range teamSyn.OnCallFor {
+		if _, ok := products[pID]; !ok {
+			return team, fmt.Errorf("on_call_for: unknown product id '%s'", pID)
+		}
+		if _, ok := team.OnCallFor[pID]; ok {
+			return team, fmt.Errorf("on_call_for: duplicate product id '%s'", pID)
+		}
+		team.OnCallFor[pID] = struct{}{}
+	}
+
 	// Validate
 	if team.IsCentral && len(team.WorkOn) > 0 {
 		return team, fmt.Errorf("specify either that the team is central or which products they work on, not both")
@@ -62,8 +95,22 @@ func newTeam(cnf *Config, teamSyn syntax.Team, products map[string]Product) (Tea
 
 func (lhs Team) Equal(rhs Team) bool {
 	return lhs.ID == rhs.ID &&
-		maps.Equal(lhs.Members, rhs.Members) &&
+		maps.EqualFunc(lhs.Members, rhs.Members, Membership.Equal) &&
 		maps.Equal(lhs.WorkOn, rhs.WorkOn) &&
 		lhs.IsCentral == rhs.IsCentral &&
-		lhs.OnlyNonProd == rhs.OnlyNonProd
+		lhs.OnlyNonProd == rhs.OnlyNonProd &&
+		maps.Equal(lhs.OnCallFor, rhs.OnCallFor)
+}
+
+// ActiveMembers returns the members of the team whose membership is active at time t
+func (team Team) ActiveMembers(t time.Time) iter.Seq[Ident] {
+	return func(yield func(Ident) bool) {
+		for ident, m := range team.Members {
+			if m.IsActive(t) {
+				if !yield(ident) {
+					return
+				}
+			}
+		}
+	}
 }
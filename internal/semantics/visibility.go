@@ -0,0 +1,76 @@
+package semantics
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+type Visibility int
+
+const (
+	VisibilityPublic     Visibility = iota // any product may consume the interface
+	VisibilityRestricted                   // only the allowed consumers may
+	VisibilityPrivate                      // only the for_product may
+)
+
+func (imSem *InterfaceMetadata) setVisibility(imSyn syntax.InterfaceMetadata, parent *InterfaceMetadata) error {
+	if imSyn.Visibility == "" && imSyn.ForProduct == nil && imSyn.AllowedConsumers == nil && parent != nil {
+		imSem.Visibility = parent.Visibility
+		imSem.AllowedConsumers = parent.AllowedConsumers
+		return nil
+	}
+	switch imSyn.Visibility {
+	case "":
+		if imSem.ForProduct != nil {
+			imSem.Visibility = VisibilityPrivate
+		} else if imSyn.AllowedConsumers != nil {
+			imSem.Visibility = VisibilityRestricted
+		}
+	case "public":
+		imSem.Visibility = VisibilityPublic
+	case "restricted":
+		imSem.Visibility = VisibilityRestricted
+	case "private":
+		imSem.Visibility = VisibilityPrivate
+	default:
+		return fmt.Errorf("visibility: should be one of public, restricted, private")
+	}
+	if imSem.Visibility == VisibilityPrivate && imSem.ForProduct == nil {
+		return fmt.Errorf("visibility: private interfaces need a for_product")
+	}
+	if imSem.Visibility != VisibilityPrivate && imSyn.ForProduct != nil {
+		return fmt.Errorf("visibility: for_product is only for private interfaces")
+	}
+	if imSem.Visibility == VisibilityRestricted && len(imSyn.AllowedConsumers) == 0 {
+		return fmt.Errorf("visibility: restricted interfaces need allowed_consumers")
+	}
+	if imSem.Visibility != VisibilityRestricted && imSyn.AllowedConsumers != nil {
+		return fmt.Errorf("visibility: allowed_consumers is only for restricted interfaces")
+	}
+	imSem.AllowedConsumers = map[string]struct{}{}
+	for _, pID := range imSyn.AllowedConsumers {
+		if _, ok := imSem.AllowedConsumers[pID]; ok {
+			return fmt.Errorf("allowed_consumers: duplicate product id '%s'", pID)
+		}
+		imSem.AllowedConsumers[pID] = struct{}{}
+	}
+	return nil
+}
+
+// mayBeConsumedBy returns whether the product with id pID may consume the interface
+func (imSem InterfaceMetadata) mayBeConsumedBy(pID string) bool {
+	switch imSem.Visibility {
+	case VisibilityRestricted:
+		_, ok := imSem.AllowedConsumers[pID]
+		return ok
+	case VisibilityPrivate:
+		return *imSem.ForProduct == pID
+	}
+	return true
+}
+
+func (lhs InterfaceMetadata) equalVisibility(rhs InterfaceMetadata) bool {
+	return lhs.Visibility == rhs.Visibility && maps.Equal(lhs.AllowedConsumers, rhs.AllowedConsumers)
+}
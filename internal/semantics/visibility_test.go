@@ -0,0 +1,105 @@
+package semantics
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/syntax"
+)
+
+func TestSetVisibility(t *testing.T) {
+	b := "b"
+	parent := &InterfaceMetadata{Visibility: VisibilityRestricted, AllowedConsumers: map[string]struct{}{"a": {}}}
+	tests := []struct {
+		imSyn   syntax.InterfaceMetadata
+		parent  *InterfaceMetadata
+		want    Visibility
+		wantErr bool
+	}{
+		{
+			want: VisibilityPublic,
+		},
+		{
+			parent: parent,
+			want:   VisibilityRestricted,
+		},
+		{
+			imSyn:  syntax.InterfaceMetadata{Visibility: "public"},
+			parent: parent,
+			want:   VisibilityPublic,
+		},
+		{
+			imSyn: syntax.InterfaceMetadata{ForProduct: &b},
+			want:  VisibilityPrivate,
+		},
+		{
+			imSyn: syntax.InterfaceMetadata{AllowedConsumers: []string{"a", "b"}},
+			want:  VisibilityRestricted,
+		},
+		{
+			imSyn: syntax.InterfaceMetadata{Visibility: "private", ForProduct: &b},
+			want:  VisibilityPrivate,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{Visibility: "private"},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{Visibility: "public", ForProduct: &b},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{Visibility: "restricted"},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{Visibility: "public", AllowedConsumers: []string{"a"}},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{AllowedConsumers: []string{"a", "a"}},
+			wantErr: true,
+		},
+		{
+			imSyn:   syntax.InterfaceMetadata{Visibility: "secret"},
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		imSem := InterfaceMetadata{}
+		if err := imSem.setForProduct(test.imSyn, test.parent); err != nil {
+			t.Fatalf("test %d: setForProduct: %v", i, err)
+		}
+		err := imSem.setVisibility(test.imSyn, test.parent)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: setVisibility(%v): expected an error", i, test.imSyn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: setVisibility(%v): %v", i, test.imSyn, err)
+		} else if imSem.Visibility != test.want {
+			t.Errorf("test %d: setVisibility(%v): got %v, want %v", i, test.imSyn, imSem.Visibility, test.want)
+		}
+	}
+}
+
+func TestMayBeConsumedBy(t *testing.T) {
+	b := "b"
+	tests := []struct {
+		imSem InterfaceMetadata
+		pID   string
+		want  bool
+	}{
+		{imSem: InterfaceMetadata{}, pID: "a", want: true},
+		{imSem: InterfaceMetadata{Visibility: VisibilityRestricted, AllowedConsumers: map[string]struct{}{"a": {}}}, pID: "a", want: true},
+		{imSem: InterfaceMetadata{Visibility: VisibilityRestricted, AllowedConsumers: map[string]struct{}{"a": {}}}, pID: "b", want: false},
+		{imSem: InterfaceMetadata{Visibility: VisibilityPrivate, ForProduct: &b}, pID: "b", want: true},
+		{imSem: InterfaceMetadata{Visibility: VisibilityPrivate, ForProduct: &b}, pID: "a", want: false},
+	}
+	for i, test := range tests {
+		if got := test.imSem.mayBeConsumedBy(test.pID); got != test.want {
+			t.Errorf("test %d: mayBeConsumedBy(%q) = %v, want %v", i, test.pID, got, test.want)
+		}
+	}
+}
@@ -29,7 +29,8 @@ func newAccountCache(ctx context.Context, semCnf *semantics.Config, cnf *Config,
 	return
 }
 
-func (c *accountCache) match(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, om semantics.ObjMatcher, o *matchedAccountObjs) error {
+func (c *accountCache) match(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, om semantics.ObjMatcher, withColumns bool,
+	o *matchedAccountObjs) error {
 	// will modify both c and o
 	err := c.matchDBs(ctx, semCnf, cnf, conn, om, o)
 	if err != nil {
@@ -41,7 +42,7 @@ func (c *accountCache) match(ctx context.Context, semCnf *semantics.Config, cnf
 			return err
 		}
 		for schema, schemaObjs := range dbObjs.getSchemas() {
-			err = c.matchObjects(ctx, conn, db, schema, om, schemaObjs)
+			err = c.matchObjects(ctx, conn, db, schema, om, withColumns, schemaObjs)
 			if err != nil {
 				return err
 			}
@@ -107,7 +108,8 @@ func (c *accountCache) matchSchemas(ctx context.Context, conn *sql.DB, db semant
 	return nil
 }
 
-func (c *accountCache) matchObjects(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident, om semantics.ObjMatcher, o *matchedSchemaObjs) error {
+func (c *accountCache) matchObjects(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident, om semantics.ObjMatcher,
+	withColumns bool, o *matchedSchemaObjs) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	if !c.hasDB(db) {
@@ -120,9 +122,9 @@ func (c *accountCache) matchObjects(ctx context.Context, conn *sql.DB, db semant
 	}
 	c.dbs[db].schemas[schema].mu.Lock() // get a (write) lock on this schema
 	defer c.dbs[db].schemas[schema].mu.Unlock()
-	if o.version == c.dbs[db].schemas[schema].version {
-		// cache entry is stale
-		if err := c.dbs[db].schemas[schema].refreshObjects(ctx, conn, db, schema); err != nil {
+	if o.version == c.dbs[db].schemas[schema].version || withColumns && !c.dbs[db].schemas[schema].hasColumns {
+		// cache entry is stale, or it does not have the columns we need
+		if err := c.dbs[db].schemas[schema].refreshObjects(ctx, conn, db, schema, withColumns); err != nil {
 			return err
 		}
 	}
@@ -130,7 +132,7 @@ func (c *accountCache) matchObjects(ctx context.Context, conn *sql.DB, db semant
 	// Next, we overwrite whatever objects o may have had; but note that we would have set it to nil to save memory; see schema_objs.go
 	o.objects = map[semantics.Ident]ObjAttr{}
 	for k, v := range c.dbs[db].schemas[schema].objects {
-		if !om.DisjointFromObject(db, schema, k) {
+		if !om.DisjointFromObject(db, schema, objName(v.ObjectType, k)) {
 			o.objects[k] = v
 		}
 	}
@@ -22,11 +22,12 @@ type AggDBObjs struct {
 	// Grants to the readDBRole
 	isUsageGrantedOnFutureSchemasToReadDBRole bool
 	// Small lookup table, first index rows, second index columns
-	//   		0: PrvSelect	1: PrvRefernces
+	//   		0: PrvSelect	1: PrvRefernces	2: PrvUsage
 	// 0: ObjTable
 	// 1: ObjView
+	// ... see objTypesObjectLevel
 	//
-	isPrivilegeOnFutureObjectGrantedToReadDBRole [2][2]bool
+	isPrivilegeOnFutureObjectGrantedToReadDBRole [len(objTypesObjectLevel)][3]bool
 	revokeGrantsToReadDBRole                     []Grant
 	revokeFutureGrantsToReadDBRole               []FutureGrant
 
@@ -76,10 +77,12 @@ func (o AggDBObjs) setFutureGrantTo(m Mode, g FutureGrant) AggDBObjs {
 				o.isUsageGrantedOnFutureSchemasToReadDBRole = true
 			}
 			// Ignore; unmanaged grant
-		case ObjTpTable, ObjTpView:
+		case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+			ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 			switch g.Privileges[0].Privilege {
-			case PrvSelect, PrvReferences:
-				o.isPrivilegeOnFutureObjectGrantedToReadDBRole[g.GrantedOn.getIdxObjectLevel()][g.Privileges[0].Privilege.getIdxObjectLevel()] = true
+			case PrvSelect, PrvReferences, PrvUsage:
+				i, _ := g.GrantedOn.getIdxObjectLevel()
+				o.isPrivilegeOnFutureObjectGrantedToReadDBRole[i][g.Privileges[0].Privilege.getIdxObjectLevel()] = true
 			}
 			// Ignore; unmanaged grant
 		}
@@ -88,7 +91,9 @@ func (o AggDBObjs) setFutureGrantTo(m Mode, g FutureGrant) AggDBObjs {
 		case ObjTpSchema:
 			switch g.Privileges[0].Privilege {
 			case PrvCreate:
-				o.isCreateObjectOnFutureSchemasGrantedToProductWriteRole[g.Privileges[0].CreateObjectType.getIdxObjectLevel()] = true
+				if i, ok := g.Privileges[0].CreateObjectType.getIdxObjectLevel(); ok {
+					o.isCreateObjectOnFutureSchemasGrantedToProductWriteRole[i] = true
+				}
 			}
 			// Ignore, unmanaged grant
 		}
@@ -109,10 +114,12 @@ func (o AggDBObjs) hasFutureGrantTo(m Mode, grantedOn ObjType, p PrivilegeComple
 			case PrvUsage:
 				return o.isUsageGrantedOnFutureSchemasToReadDBRole
 			}
-		case ObjTpTable, ObjTpView:
+		case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+			ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 			switch p.Privilege {
-			case PrvSelect, PrvReferences:
-				return o.isPrivilegeOnFutureObjectGrantedToReadDBRole[grantedOn.getIdxObjectLevel()][p.Privilege.getIdxObjectLevel()]
+			case PrvSelect, PrvReferences, PrvUsage:
+				i, _ := grantedOn.getIdxObjectLevel()
+				return o.isPrivilegeOnFutureObjectGrantedToReadDBRole[i][p.Privilege.getIdxObjectLevel()]
 			}
 		}
 	case ModeWrite:
@@ -120,7 +127,9 @@ func (o AggDBObjs) hasFutureGrantTo(m Mode, grantedOn ObjType, p PrivilegeComple
 		case ObjTpSchema:
 			switch p.Privilege {
 			case PrvCreate:
-				return o.isCreateObjectOnFutureSchemasGrantedToProductWriteRole[grantedOn.getIdxObjectLevel()]
+				if i, ok := p.CreateObjectType.getIdxObjectLevel(); ok {
+					return o.isCreateObjectOnFutureSchemasGrantedToProductWriteRole[i]
+				}
 			}
 		}
 	}
@@ -186,7 +195,8 @@ func (o AggDBObjs) setFutureGrants(ctx context.Context, semCnf *semantics.Config
 					} else {
 						o = o.setRevokeFutureGrantTo(ModeRead, g)
 					}
-				case ObjTpTable, ObjTpView:
+				case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+					ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 					if o.MatchAllObjects {
 						o = o.setFutureGrantTo(ModeRead, g)
 					} else {
@@ -256,7 +266,8 @@ func (o AggDBObjs) setGrants(ctx context.Context, semCnf *semantics.Config, cnf
 						o = o.setRevokeGrantTo(ModeRead, g)
 					}
 				} // Ignore this grant, it is correct, even if we did not know about the object's existence yet (result of FUTURE grant, probably)
-			case ObjTpTable, ObjTpView:
+			case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+				ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 				if o.hasObject(g.Schema, g.Object) {
 					if o.Schemas[g.Schema].Objects[g.Object].ObjectType != g.GrantedOn {
 						// A table may have been dropped and a view with the same name created or vice versa
@@ -264,7 +275,7 @@ func (o AggDBObjs) setGrants(ctx context.Context, semCnf *semantics.Config, cnf
 						return o, ErrObjectNotExistOrAuthorized
 					}
 					o.Schemas[g.Schema].Objects[g.Object] = o.Schemas[g.Schema].Objects[g.Object].setGrantTo(ModeRead, g)
-				} else if oms.DisjointFromObject(g.Database, g.Schema, g.Object) {
+				} else if oms.DisjointFromObject(g.Database, g.Schema, objName(g.GrantedOn, g.Object)) {
 					o = o.setRevokeGrantTo(ModeRead, g)
 				} // Ignore this grant, it is correct, even if we did not know about the object's existence yet (result of FUTURE grant, probably)
 			}
@@ -300,11 +311,11 @@ func (o AggDBObjs) pushToDoFutureGrants(yield func(FutureGrant) bool) bool {
 		}
 	}
 	if o.MatchAllObjects {
-		for _, ot := range [2]ObjType{ObjTpTable, ObjTpView} {
+		for _, ot := range objTypesObjectLevel {
 			prvs := []PrivilegeComplete{}
-			for _, p := range [2]PrivilegeComplete{PrivilegeComplete{Privilege: PrvSelect}, PrivilegeComplete{Privilege: PrvReferences}} {
-				if !o.hasFutureGrantTo(ModeRead, ot, p) {
-					prvs = append(prvs, p)
+			for _, p := range ot.getPrivilegesObjectLevel() {
+				if !o.hasFutureGrantTo(ModeRead, ot, PrivilegeComplete{Privilege: p}) {
+					prvs = append(prvs, PrivilegeComplete{Privilege: p})
 				}
 			}
 			if len(prvs) > 0 {
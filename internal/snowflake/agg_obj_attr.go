@@ -7,11 +7,14 @@ import (
 type AggObjAttr struct {
 	ObjectType ObjType
 	Owner      semantics.Ident
+	Columns    []Column // nil: not loaded
 
 	// set when grant() is called on AggDBObjs
 	isSelectGrantedToReadDBRole     bool
 	isReferencesGrantedToReadDBRole bool
+	isUsageGrantedToReadDBRole      bool
 	isOwnedByProductWriteRole       bool
+	isMonitorGrantedToOperateRole   bool
 }
 
 func (o AggObjAttr) setGrantTo(m Mode, g Grant) AggObjAttr {
@@ -22,6 +25,8 @@ func (o AggObjAttr) setGrantTo(m Mode, g Grant) AggObjAttr {
 			o.isSelectGrantedToReadDBRole = true
 		case PrvReferences:
 			o.isReferencesGrantedToReadDBRole = true
+		case PrvUsage:
+			o.isUsageGrantedToReadDBRole = true
 		}
 		// Ignore; unmanaged grant
 	case ModeWrite:
@@ -30,6 +35,12 @@ func (o AggObjAttr) setGrantTo(m Mode, g Grant) AggObjAttr {
 			o.isOwnedByProductWriteRole = true
 		}
 		// Ignore; unmanaged grant
+	case ModeOperate:
+		switch g.Privileges[0].Privilege {
+		case PrvMonitor:
+			o.isMonitorGrantedToOperateRole = true
+		}
+		// Ignore; unmanaged grant
 	default:
 		panic("not implemented")
 	}
@@ -44,6 +55,13 @@ func (o AggObjAttr) hasGrantTo(m Mode, p Privilege) bool {
 			return o.isSelectGrantedToReadDBRole
 		case PrvReferences:
 			return o.isReferencesGrantedToReadDBRole
+		case PrvUsage:
+			return o.isUsageGrantedToReadDBRole
+		}
+	case ModeOperate:
+		switch p {
+		case PrvMonitor:
+			return o.isMonitorGrantedToOperateRole
 		}
 	}
 	return false
@@ -51,7 +69,7 @@ func (o AggObjAttr) hasGrantTo(m Mode, p Privilege) bool {
 
 func (o AggObjAttr) pushToDoGrants(yield func(Grant) bool, dbRole DatabaseRole, schema semantics.Ident, obj semantics.Ident) bool {
 	prvs := []PrivilegeComplete{}
-	for _, p := range [2]Privilege{PrvSelect, PrvReferences} {
+	for _, p := range o.ObjectType.getPrivilegesObjectLevel() {
 		if !o.hasGrantTo(ModeRead, p) {
 			prvs = append(prvs, PrivilegeComplete{Privilege: p})
 		}
@@ -10,8 +10,8 @@ type AggSchemaObjs struct {
 
 	// set while grants are being set
 	isUsageGrantedToReadDBRole                   bool
-	isPrivilegeOnFutureObjectGrantedToReadDBRole [2][2]bool // [ObjType][Privilege]
-	isCreateGrantedToProductWriteRole            [2]bool    // [ObjType]
+	isPrivilegeOnFutureObjectGrantedToReadDBRole [len(objTypesObjectLevel)][3]bool // [ObjType][Privilege]
+	isCreateGrantedToProductWriteRole            [2]bool                           // [ObjType]
 }
 
 func newAggSchemaObjs(o SchemaObjs) AggSchemaObjs {
@@ -20,7 +20,7 @@ func newAggSchemaObjs(o SchemaObjs) AggSchemaObjs {
 		MatchAllObjects: o.MatchAllObjects,
 	}
 	for k, v := range o.Objects {
-		r.Objects[k] = AggObjAttr{ObjectType: v.ObjectType, Owner: v.Owner}
+		r.Objects[k] = AggObjAttr{ObjectType: v.ObjectType, Owner: v.Owner, Columns: v.Columns}
 	}
 	return r
 }
@@ -33,10 +33,12 @@ func (o AggSchemaObjs) hasObject(k semantics.Ident) bool {
 func (o AggSchemaObjs) setFutureGrantTo(_ Mode, g FutureGrant) AggSchemaObjs {
 	// Currently, only ModeRead privileges on future objects in schemas are managed
 	switch g.GrantedOn {
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 		switch g.Privileges[0].Privilege {
-		case PrvSelect, PrvReferences:
-			o.isPrivilegeOnFutureObjectGrantedToReadDBRole[g.GrantedOn.getIdxObjectLevel()][g.Privileges[0].Privilege.getIdxObjectLevel()] = true
+		case PrvSelect, PrvReferences, PrvUsage:
+			i, _ := g.GrantedOn.getIdxObjectLevel()
+			o.isPrivilegeOnFutureObjectGrantedToReadDBRole[i][g.Privileges[0].Privilege.getIdxObjectLevel()] = true
 		}
 		// Ignore; unmanaged grant
 	}
@@ -47,10 +49,12 @@ func (o AggSchemaObjs) setFutureGrantTo(_ Mode, g FutureGrant) AggSchemaObjs {
 func (o AggSchemaObjs) hasFutureGrantTo(_ Mode, grantedOn ObjType, p Privilege) bool {
 	// Currently, only ModeRead privileges on future objects in schemas are managed
 	switch grantedOn {
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 		switch p {
-		case PrvSelect, PrvReferences:
-			return o.isPrivilegeOnFutureObjectGrantedToReadDBRole[grantedOn.getIdxObjectLevel()][p.getIdxObjectLevel()]
+		case PrvSelect, PrvReferences, PrvUsage:
+			i, _ := grantedOn.getIdxObjectLevel()
+			return o.isPrivilegeOnFutureObjectGrantedToReadDBRole[i][p.getIdxObjectLevel()]
 		}
 	}
 	return false
@@ -62,7 +66,8 @@ func (o AggSchemaObjs) setGrantTo(m Mode, g Grant) AggSchemaObjs {
 	}
 	if m == ModeWrite && g.Privileges[0].Privilege == PrvCreate &&
 		(g.Privileges[0].CreateObjectType == ObjTpTable || g.Privileges[0].CreateObjectType == ObjTpView) {
-		o.isCreateGrantedToProductWriteRole[g.Privileges[0].CreateObjectType.getIdxObjectLevel()] = true
+		i, _ := g.Privileges[0].CreateObjectType.getIdxObjectLevel()
+		o.isCreateGrantedToProductWriteRole[i] = true
 	}
 	// Ignore; unmanaged grant
 	return o
@@ -78,7 +83,9 @@ func (o AggSchemaObjs) hasGrantTo(m Mode, p PrivilegeComplete) bool {
 	case ModeWrite:
 		switch p.Privilege {
 		case PrvCreate:
-			return o.isCreateGrantedToProductWriteRole[p.CreateObjectType.getIdxObjectLevel()]
+			if i, ok := p.CreateObjectType.getIdxObjectLevel(); ok {
+				return o.isCreateGrantedToProductWriteRole[i]
+			}
 		}
 	}
 	return false
@@ -86,9 +93,9 @@ func (o AggSchemaObjs) hasGrantTo(m Mode, p PrivilegeComplete) bool {
 
 func (o AggSchemaObjs) pushToDoFutureGrants(yield func(FutureGrant) bool, dbRole DatabaseRole, schema semantics.Ident) bool {
 	if o.MatchAllObjects {
-		for _, ot := range [2]ObjType{ObjTpTable, ObjTpView} {
+		for _, ot := range objTypesObjectLevel {
 			prvs := []PrivilegeComplete{}
-			for _, p := range [2]Privilege{PrvSelect, PrvReferences} {
+			for _, p := range ot.getPrivilegesObjectLevel() {
 				if !o.hasFutureGrantTo(ModeRead, ot, p) {
 					prvs = append(prvs, PrivilegeComplete{Privilege: p})
 				}
@@ -0,0 +1,86 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+type BreakGlassDecoded struct {
+	User      string `yaml:"user"`
+	ProductID string `yaml:"product_id"`
+	DTAP      string `yaml:"dtap"`
+	Mode      string `yaml:"mode"`
+	Reason    string `yaml:"reason"`
+	Ticket    string `yaml:"ticket"`
+	ExpiresAt string `yaml:"expires_at"` // RFC 3339 timestamp, or a date, meaning the start of that day, UTC
+}
+
+// A BreakGlass grants a product role to a user temporarily, e.g., the production write role during a severe incident.
+// Grupr grants the role until the break glass expires; after that, the grant is no longer declared, and grupr revokes
+// it, like any other grant of a product role to a user that is not in the YAML. Grupr records every activation and
+// every revocation after expiry in the break_glass_log table in the schema configured for grupr.
+type BreakGlass struct {
+	User semantics.Ident
+	semantics.ProductDTAPID
+	Mode      Mode
+	Reason    string
+	Ticket    string
+	ExpiresAt time.Time
+}
+
+func parseExpiresAt(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func newBreakGlass(semCnf *semantics.Config, b BreakGlassDecoded) (BreakGlass, error) {
+	r := BreakGlass{
+		ProductDTAPID: semantics.ProductDTAPID{ProductID: b.ProductID, DTAP: b.DTAP},
+		Reason:        b.Reason,
+		Ticket:        b.Ticket,
+	}
+	var err error
+	if r.User, err = semantics.NewIdentStripQuotesIfAny(b.User, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr); err != nil {
+		return r, err
+	}
+	if r.Mode, err = ParseMode(b.Mode); err != nil {
+		return r, err
+	}
+	if r.Reason == "" || r.Ticket == "" {
+		return r, fmt.Errorf("reason and ticket are required")
+	}
+	if r.ExpiresAt, err = parseExpiresAt(b.ExpiresAt); err != nil {
+		return r, fmt.Errorf("invalid expires_at '%s', use a timestamp like 2006-01-02T15:04:05Z, or a date like 2006-01-02", b.ExpiresAt)
+	}
+	return r, nil
+}
+
+func (b BreakGlass) String() string {
+	return fmt.Sprintf("break glass for user '%v', product '%s', dtap '%s', mode '%v', ticket '%s'", b.User, b.ProductID, b.DTAP, b.Mode, b.Ticket)
+}
+
+func (b BreakGlass) record(ctx context.Context, cnf *Config, conn *sql.DB, event string) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`INSERT INTO %s.%s.break_glass_log (event, user_name, product_id, dtap, mode, reason, ticket, expires_at, recorded_at)
+SELECT ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP()`, cnf.Database, cnf.Schema),
+		event, string(b.User), b.ProductID, b.DTAP, b.Mode.String(), b.Reason, b.Ticket, b.ExpiresAt)
+}
+
+func CreateBreakGlassLogTable(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s.break_glass_log (
+	event varchar,
+	user_name varchar,
+	product_id varchar,
+	dtap varchar,
+	mode varchar,
+	reason varchar,
+	ticket varchar,
+	expires_at timestamp_ltz,
+	recorded_at timestamp_ltz
+)`, cnf.Database, cnf.Schema))
+}
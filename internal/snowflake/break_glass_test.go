@@ -0,0 +1,36 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpiresAt(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{s: "2026-01-02T15:04:05Z", want: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{s: "2026-01-02T17:04:05+02:00", want: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{s: "2026-01-02", want: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{s: "", wantErr: true},
+		{s: "2026-01-02 15:04:05", wantErr: true},
+		{s: "02-01-2026", wantErr: true},
+		{s: "2026-13-01", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseExpiresAt(test.s)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseExpiresAt(%q): expected an error, got %v", test.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseExpiresAt(%q): %v", test.s, err)
+		} else if !got.Equal(test.want) {
+			t.Errorf("parseExpiresAt(%q) = %v, want %v", test.s, got, test.want)
+		}
+	}
+}
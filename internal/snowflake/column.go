@@ -0,0 +1,95 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+type ColumnID struct {
+	Database semantics.Ident
+	Schema   semantics.Ident
+	Object   semantics.Ident
+	Column   semantics.Ident
+}
+
+type Column struct {
+	Object   semantics.Ident
+	Name     semantics.Ident
+	DataType string // as used in a policy signature, e.g., VARCHAR, NUMBER
+}
+
+func newColumn(obj semantics.Ident, name semantics.Ident, dataType string) (Column, error) {
+	if len(obj) == 0 || len(name) == 0 {
+		return Column{}, fmt.Errorf("zero length identifier")
+	}
+	return Column{Object: obj, Name: name, DataType: newPolicyDataType(dataType)}, nil
+}
+
+func newPolicyDataType(s string) string {
+	// SHOW COLUMNS describes data types with the names Snowflake uses internally;
+	// policy signatures need the SQL names.
+	switch s {
+	case "FIXED":
+		return "NUMBER"
+	case "REAL":
+		return "FLOAT"
+	case "TEXT":
+		return "VARCHAR"
+	}
+	return s
+}
+
+const maxShowColumnsRows = 10000
+
+func QueryColumns(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) iter.Seq2[Column, error] {
+	return func(yield func(Column, error) bool) {
+		// Unlike SHOW OBJECTS, SHOW COLUMNS can not paginate; it returns at most 10K rows. If we would carry on with
+		// the columns we got, policies on the missing ones would silently not be applied, so we return an error.
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW COLUMNS IN SCHEMA IDENTIFIER($$%s.%s$$) ->>
+SELECT
+    "table_name" AS table_name
+  , "column_name" AS column_name
+  , PARSE_JSON("data_type"):"type"::VARCHAR AS data_type
+FROM $1
+`, db, schema))
+		if err != nil {
+			if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+				err = ErrObjectNotExistOrAuthorized
+			}
+			yield(Column{}, err)
+			return
+		}
+		defer rows.Close()
+		n := 0
+		for rows.Next() {
+			n += 1
+			if n == maxShowColumnsRows {
+				yield(Column{}, fmt.Errorf("QueryColumns: schema %s.%s has %d columns or more, can not manage column policies", db, schema, n))
+				return
+			}
+			var obj semantics.Ident
+			var name semantics.Ident
+			var dataType string
+			if err = rows.Scan(&obj, &name, &dataType); err != nil {
+				err = fmt.Errorf("QueryColumns: error scanning row: %w", err)
+				yield(Column{}, err)
+				return
+			}
+			if col, err := newColumn(obj, name, dataType); err != nil {
+				yield(Column{}, err)
+				return
+			} else if !yield(col, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("QueryColumns: error after looping over results: %w", err)
+			yield(Column{}, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestNewColumn(t *testing.T) {
+	tests := []struct {
+		obj      semantics.Ident
+		name     semantics.Ident
+		dataType string
+		want     string
+		wantErr  bool
+	}{
+		{obj: "CUSTOMER", name: "SSN", dataType: "TEXT", want: "VARCHAR"},
+		{obj: "CUSTOMER", name: "AGE", dataType: "FIXED", want: "NUMBER"},
+		{obj: "CUSTOMER", name: "SCORE", dataType: "REAL", want: "FLOAT"},
+		{obj: "CUSTOMER", name: "BIRTH_DATE", dataType: "DATE", want: "DATE"},
+		{obj: "CUSTOMER", name: "my column", dataType: "TIMESTAMP_NTZ", want: "TIMESTAMP_NTZ"},
+		{obj: "", name: "SSN", dataType: "TEXT", wantErr: true},
+		{obj: "CUSTOMER", name: "", dataType: "TEXT", wantErr: true},
+	}
+	for _, test := range tests {
+		col, err := newColumn(test.obj, test.name, test.dataType)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("newColumn(%v, %v, %s): expected an error", test.obj, test.name, test.dataType)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newColumn(%v, %v, %s): %v", test.obj, test.name, test.dataType, err)
+		} else if col.DataType != test.want || col.Object != test.obj || col.Name != test.name {
+			t.Errorf("newColumn(%v, %v, %s) = %v, want data type %s", test.obj, test.name, test.dataType, col, test.want)
+		}
+	}
+}
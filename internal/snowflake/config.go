@@ -3,8 +3,10 @@ package snowflake
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
 	"github.com/rwberendsen/grupr/internal/util"
@@ -27,6 +29,15 @@ type Config struct {
 	SystemDefinedRoles      []semantics.Ident
 	DatabaseRolePrivileges  map[Mode]map[GrantTemplate]struct{}
 	ProductRolePrivileges   map[Mode]map[GrantTemplate]struct{}
+	HashSaltScope           string
+	ManageTags              bool
+	ManageSessionPolicies   bool
+	AutoSuspend             bool          // set from the --auto-suspend command line flag
+	DropWarehouses          bool          // set from the --drop-warehouses command line flag
+	ConfiscateAfter         time.Duration // set from the --confiscate-after command line flag; zero means never, unless set per product
+	ProvisionUsers          bool
+	UserScope               *regexp.Regexp // users grupr may create, disable, and enable, if it provisions users
+	RSAPublicKeyDir         string         // where to find the RSA public keys of service account users, as USER.pub
 	DryRun                  bool
 }
 
@@ -48,7 +59,8 @@ func GetConfig(semCnf *semantics.Config) (*Config, error) {
 			semantics.Ident("SECURITYADMIN"),
 			semantics.Ident("USERADMIN"),
 		},
-		DryRun: true,
+		HashSaltScope: "account",
+		DryRun:        true,
 	}
 
 	if user, ok := os.LookupEnv("GRUPR_SNOWFLAKE_USER"); !ok {
@@ -152,6 +164,52 @@ func GetConfig(semCnf *semantics.Config) (*Config, error) {
 		}
 	}
 
+	if provisionUsers, ok := os.LookupEnv("GRUPR_SNOWFLAKE_PROVISION_USERS"); ok {
+		if b, err := strconv.ParseBool(provisionUsers); err != nil {
+			return nil, fmt.Errorf("GRUPR_SNOWFLAKE_PROVISION_USERS: %w", err)
+		} else {
+			cnf.ProvisionUsers = b
+		}
+	}
+
+	if userScope, ok := os.LookupEnv("GRUPR_SNOWFLAKE_USER_SCOPE"); ok {
+		// Anchored, so that a scope like SVC_ does not match ADMIN_SVC_X
+		if re, err := regexp.Compile(`^(?:` + userScope + `)$`); err != nil {
+			return nil, fmt.Errorf("GRUPR_SNOWFLAKE_USER_SCOPE: %w", err)
+		} else {
+			cnf.UserScope = re
+		}
+	} else if cnf.ProvisionUsers {
+		return nil, fmt.Errorf("GRUPR_SNOWFLAKE_PROVISION_USERS: GRUPR_SNOWFLAKE_USER_SCOPE is required to provision users")
+	}
+
+	if rsaPublicKeyDir, ok := os.LookupEnv("GRUPR_SNOWFLAKE_RSA_PUBLIC_KEY_DIR"); ok {
+		cnf.RSAPublicKeyDir = rsaPublicKeyDir
+	}
+
+	if hashSaltScope, ok := os.LookupEnv("GRUPR_SNOWFLAKE_HASH_SALT_SCOPE"); ok {
+		if hashSaltScope != "account" && hashSaltScope != "product" {
+			return nil, fmt.Errorf("GRUPR_SNOWFLAKE_HASH_SALT_SCOPE: should be one of account, product")
+		}
+		cnf.HashSaltScope = hashSaltScope
+	}
+
+	if manageTags, ok := os.LookupEnv("GRUPR_SNOWFLAKE_MANAGE_TAGS"); ok {
+		if b, err := strconv.ParseBool(manageTags); err != nil {
+			return nil, fmt.Errorf("GRUPR_SNOWFLAKE_MANAGE_TAGS: %w", err)
+		} else {
+			cnf.ManageTags = b
+		}
+	}
+
+	if manageSessionPolicies, ok := os.LookupEnv("GRUPR_SNOWFLAKE_MANAGE_SESSION_POLICIES"); ok {
+		if b, err := strconv.ParseBool(manageSessionPolicies); err != nil {
+			return nil, fmt.Errorf("GRUPR_SNOWFLAKE_MANAGE_SESSION_POLICIES: %w", err)
+		} else {
+			cnf.ManageSessionPolicies = b
+		}
+	}
+
 	cnf.DatabaseRolePrivileges = map[Mode]map[GrantTemplate]struct{}{}
 	cnf.DatabaseRolePrivileges[ModeRead] = map[GrantTemplate]struct{}{
 		GrantTemplate{
@@ -162,22 +220,14 @@ func GetConfig(semCnf *semantics.Config) (*Config, error) {
 			PrivilegeComplete: PrivilegeComplete{Privilege: PrvUsage},
 			GrantedOn:         ObjTpSchema,
 		}: {},
-		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvSelect},
-			GrantedOn:         ObjTpTable,
-		}: {},
-		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvSelect},
-			GrantedOn:         ObjTpView,
-		}: {},
-		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvReferences},
-			GrantedOn:         ObjTpTable,
-		}: {},
-		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvReferences},
-			GrantedOn:         ObjTpView,
-		}: {},
+	}
+	for _, ot := range objTypesObjectLevel {
+		for _, p := range ot.getPrivilegesObjectLevel() {
+			cnf.DatabaseRolePrivileges[ModeRead][GrantTemplate{
+				PrivilegeComplete: PrivilegeComplete{Privilege: p},
+				GrantedOn:         ot,
+			}] = struct{}{}
+		}
 	}
 
 	cnf.ProductRolePrivileges = map[Mode]map[GrantTemplate]struct{}{}
@@ -211,21 +261,50 @@ func GetConfig(semCnf *semantics.Config) (*Config, error) {
 			GrantedOn:         ObjTpSchema,
 		}: {},
 		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOwnership},
-			GrantedOn:         ObjTpTable,
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvUsage},
+			GrantedOn:         ObjTpWarehouse,
 		}: {},
 		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOwnership},
-			GrantedOn:         ObjTpView,
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOperate},
+			GrantedOn:         ObjTpWarehouse,
 		}: {},
+	}
+	cnf.ProductRolePrivileges[ModeOperate] = map[GrantTemplate]struct{}{
 		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvUsage},
-			GrantedOn:         ObjTpWarehouse,
+			PrivilegeComplete:         PrivilegeComplete{Privilege: PrvUsage},
+			GrantedOn:                 ObjTpRole,
+			GrantedRoleIsGruprManaged: util.NewTrue(),
 		}: {},
 		GrantTemplate{
 			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOperate},
 			GrantedOn:         ObjTpWarehouse,
 		}: {},
+		GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor},
+			GrantedOn:         ObjTpWarehouse,
+		}: {},
+		GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor},
+			GrantedOn:         ObjTpPipe,
+		}: {},
+		GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor},
+			GrantedOn:         ObjTpTask,
+		}: {},
+	}
+	for _, m := range [2]Mode{ModeRead, ModeWrite} {
+		for _, p := range [3]Privilege{PrvRead, PrvWrite, PrvUsage} {
+			cnf.ProductRolePrivileges[m][GrantTemplate{
+				PrivilegeComplete: PrivilegeComplete{Privilege: p},
+				GrantedOn:         ObjTpStage,
+			}] = struct{}{}
+		}
+	}
+	for _, ot := range objTypesObjectLevel {
+		cnf.ProductRolePrivileges[ModeWrite][GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOwnership},
+			GrantedOn:         ot,
+		}] = struct{}{}
 	}
 
 	if dryRun, ok := os.LookupEnv("GRUPR_SNOWFLAKE_DRY_RUN"); ok {
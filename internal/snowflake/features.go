@@ -10,13 +10,19 @@ import (
 
 type features struct {
 	// Decoded YAML for Snowflake specific features like warehouses and stages
-	warehouses []WarehouseDecoded
-	// stages []StageDecoded ...
+	warehouses       []WarehouseDecoded
+	stages           []StageDecoded
+	resourceMonitors []ResourceMonitorDecoded
+	breakGlass       []BreakGlassDecoded
+	sessionPolicies  []SessionPolicyDecoded
 }
 
 type ElmntOr struct {
-	Warehouse *WarehouseDecoded `yaml:"warehouse,omitempty"`
-	// Stage *StageDecoded ...
+	Warehouse       *WarehouseDecoded       `yaml:"warehouse,omitempty"`
+	Stage           *StageDecoded           `yaml:"stage,omitempty"`
+	ResourceMonitor *ResourceMonitorDecoded `yaml:"resource_monitor,omitempty"`
+	BreakGlass      *BreakGlassDecoded      `yaml:"break_glass,omitempty"`
+	SessionPolicy   *SessionPolicyDecoded   `yaml:"session_policy,omitempty"`
 }
 
 func newFeatures(yamlPath string) (features, error) {
@@ -42,8 +48,24 @@ func newFeatures(yamlPath string) (features, error) {
 			feat.warehouses = append(feat.warehouses, *e.Warehouse)
 			nElements += 1
 		}
+		if e.Stage != nil {
+			feat.stages = append(feat.stages, *e.Stage)
+			nElements += 1
+		}
+		if e.ResourceMonitor != nil {
+			feat.resourceMonitors = append(feat.resourceMonitors, *e.ResourceMonitor)
+			nElements += 1
+		}
+		if e.BreakGlass != nil {
+			feat.breakGlass = append(feat.breakGlass, *e.BreakGlass)
+			nElements += 1
+		}
+		if e.SessionPolicy != nil {
+			feat.sessionPolicies = append(feat.sessionPolicies, *e.SessionPolicy)
+			nElements += 1
+		}
 		if nElements != 1 {
-			return feat, fmt.Errorf("decoding Snowflake features YAML: not exactly one object", err)
+			return feat, fmt.Errorf("decoding Snowflake features YAML: not exactly one object")
 		}
 	}
 	return feat, nil
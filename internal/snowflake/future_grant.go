@@ -55,7 +55,8 @@ func (g FutureGrant) buildSQLGrant(revoke bool) string {
 			panic("Not implemented")
 		}
 		inClause += fmt.Sprintf(`%v %s`, g.GrantedIn, g.Database)
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 		switch g.GrantedIn {
 		case ObjTpDatabase:
 			inClause += fmt.Sprintf(`%v IDENTIFIER($$%s$$)`, g.GrantedIn, g.Database)
@@ -68,7 +69,7 @@ func (g FutureGrant) buildSQLGrant(revoke bool) string {
 		panic("Not implemented")
 	}
 
-	onClause += fmt.Sprintf(`%vS`, g.GrantedOn)
+	onClause += fmt.Sprintf(`%sS`, g.GrantedOn.sql())
 	return fmt.Sprintf(`%v %s %s %s %s`, verb, privilegeClause, onClause, inClause, granteeClause)
 }
 
@@ -106,7 +107,8 @@ func newFutureGrant(privilege string, createObjType string, grantedOn string, na
 	case ObjTpSchema:
 		g.Database = semantics.Ident(rec[0])
 		g.Schema = semantics.Ident(rec[1])
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
 		g.Database = semantics.Ident(rec[0])
 		g.Schema = semantics.Ident(rec[1])
 		g.Object = semantics.Ident(rec[2])
@@ -69,7 +69,10 @@ func (g Grant) buildSQLGrant(revoke bool) string {
 		objectClause = fmt.Sprintf(`%v IDENTIFIER($$%s$$)`, g.GrantedOn, g.Database)
 	case ObjTpSchema:
 		objectClause = fmt.Sprintf(`%v IDENTIFIER($$%s.%s$$)`, g.GrantedOn, g.Database, g.Schema)
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
+		objectClause = fmt.Sprintf(`%s %s`, g.GrantedOn.sql(), objSQL(g.GrantedOn, g.Database, g.Schema, g.Object))
+	case ObjTpStage:
 		objectClause = fmt.Sprintf(`%v IDENTIFIER($$%s.%s.%s$$)`, g.GrantedOn, g.Database, g.Schema, g.Object)
 	case ObjTpWarehouse:
 		objectClause = fmt.Sprintf(`%v IDENTIFIER($$%s$$)`, g.GrantedOn, g.Object)
@@ -97,10 +100,12 @@ func newGrantToRole(privilege string, createObjType string, grantedOn string, na
 		ObjTpDatabaseRole: 2,
 		ObjTpRole:         1,
 		ObjTpSchema:       2,
-		ObjTpTable:        3,
-		ObjTpView:         3,
+		ObjTpStage:        3,
 		ObjTpWarehouse:    1,
 	}
+	for _, ot := range objTypesObjectLevel {
+		fpr[ot] = 3
+	}
 	r := csv.NewReader(strings.NewReader(name)) // handles quoted fields as they appear in name
 	r.Comma = '.'
 	r.FieldsPerRecord = fpr[g.GrantedOn]
@@ -122,7 +127,22 @@ func newGrantToRole(privilege string, createObjType string, grantedOn string, na
 	case ObjTpSchema:
 		g.Database = semantics.Ident(rec[0])
 		g.Schema = semantics.Ident(rec[1])
-	case ObjTpTable, ObjTpView:
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
+		g.Database = semantics.Ident(rec[0])
+		g.Schema = semantics.Ident(rec[1])
+		g.Object = semantics.Ident(rec[2])
+		if hasSignature(g.GrantedOn) {
+			// e.g., MY_FUNC(A NUMBER):NUMBER(38,0)
+			i := strings.Index(rec[2], "(")
+			if i == -1 {
+				return g, fmt.Errorf("missing argument signature in name of %v: '%s'", g.GrantedOn, name)
+			}
+			if g.Object, err = newSignatureIdent(semantics.Ident(rec[2][:i]), rec[2][i:]); err != nil {
+				return g, err
+			}
+		}
+	case ObjTpStage:
 		g.Database = semantics.Ident(rec[0])
 		g.Schema = semantics.Ident(rec[1])
 		g.Object = semantics.Ident(rec[2])
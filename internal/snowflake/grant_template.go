@@ -16,7 +16,7 @@ func (g GrantTemplate) buildSQLFilter() (string, int) {
 	if g.Privilege != PrvOther {
 		clauses = append(clauses, fmt.Sprintf("privilege = '%v'", g.Privilege))
 		if g.Privilege == PrvCreate && g.CreateObjectType != ObjTpOther {
-			clauses = append(clauses, fmt.Sprintf("create_object_type = '%v'", g.CreateObjectType))
+			clauses = append(clauses, fmt.Sprintf("create_object_type = '%s'", g.CreateObjectType.sql()))
 		}
 	}
 	if g.GrantedOn != ObjTpOther {
@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"iter"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
 	"github.com/rwberendsen/grupr/internal/syntax"
@@ -17,7 +20,23 @@ type Grupin struct {
 	UserGroupMappings map[string]semantics.UserGroupMapping
 
 	// Some fetch-one time reference data on objects that exist in Snowflake already
-	productRoles map[ProductRole]struct{}
+	productRoles      map[ProductRole]struct{}
+	maskingPolicies   map[semantics.Ident]MaskingPolicy
+	rowAccessPolicies map[semantics.Ident]RowAccessPolicy
+
+	// Users that product roles are granted to, or that have a session policy; v: whether it is a service account user
+	users map[semantics.Ident]bool
+
+	// Team members whose membership has not started yet; grupr does not create them yet, nor disable them
+	upcomingUsers map[semantics.Ident]struct{}
+
+	// Warehouses and resource monitors declared in the Snowflake features YAML
+	warehouses       map[semantics.Ident]WarehouseProps
+	resourceMonitors map[semantics.Ident]*ResourceMonitor
+
+	// Session policies for users that may combine product roles with some other roles, declared in the Snowflake
+	// features YAML
+	sessionPolicies map[semantics.Ident]*SessionPolicy
 
 	// The account cache, used to fetch objects by several concurrent threads, possibly from the same databases and schemas
 	accountCache *accountCache
@@ -27,12 +46,57 @@ func NewGrupin(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn
 	r := &Grupin{
 		ProductDTAPs:      map[semantics.ProductDTAPID]*ProductDTAP{},
 		UserGroupMappings: g.UserGroupMappings,
+		users:             map[semantics.Ident]bool{},
+		upcomingUsers:     map[semantics.Ident]struct{}{},
 	}
 
+	now := time.Now()
 	for pID, pSem := range g.Products {
 		for dtap, isProd := range pSem.DTAPs.All() {
 			pdID := semantics.ProductDTAPID{ProductID: pID, DTAP: dtap}
-			r.ProductDTAPs[pdID] = NewProductDTAP(pdID, isProd, pSem, r.UserGroupMappings, g.ServiceAccounts, g.Teams)
+			r.ProductDTAPs[pdID] = NewProductDTAP(pdID, isProd, pSem, r.UserGroupMappings, g.ServiceAccounts, g.Teams, g.Classes, now)
+		}
+	}
+	// Products no longer consume deprecated interfaces after their sunset date, nor interfaces whose producing product
+	// requires an approval that they do not have
+	unapproved := map[semantics.ConsumptionEdge]struct{}{}
+	for _, e := range g.UnapprovedConsumptions(now) {
+		unapproved[e] = struct{}{}
+	}
+	for _, pd := range r.ProductDTAPs {
+		for iid, sourceDTAP := range pd.Consumes {
+			if g.Products[iid.ProductID].Interfaces[iid.ID].IsSunset(now) {
+				log.Printf("WARN: product '%s', dtap '%s': interface '%s' of product '%s' is past its sunset date, revoking access",
+					pd.ProductID, pd.DTAP, iid.ID, iid.ProductID)
+			} else if _, ok := unapproved[semantics.ConsumptionEdge{InterfaceID: iid, ConsumerID: pd.ProductID}]; ok {
+				log.Printf("WARN: product '%s', dtap '%s': interface '%s' of product '%s' is consumed without its approval, revoking access",
+					pd.ProductID, pd.DTAP, iid.ID, iid.ProductID)
+			} else {
+				continue
+			}
+			delete(pd.Consumes, iid)
+			// The database roles of the source interface are granted to its consumers, so remove this one there, too
+			if source, ok := r.ProductDTAPs[semantics.ProductDTAPID{ProductID: iid.ProductID, DTAP: sourceDTAP}]; ok {
+				if i, ok := source.Interfaces[iid.ID]; ok {
+					delete(i.ConsumedBy, pd.ProductDTAPID)
+				}
+			}
+		}
+	}
+
+	for _, svc := range g.ServiceAccounts {
+		for _, ident := range svc.Idents {
+			r.users[ident] = true
+		}
+	}
+	for _, team := range g.Teams {
+		for ident := range team.ActiveMembers(now) {
+			r.users[ident] = false
+		}
+		for ident, m := range team.Members {
+			if !m.From.IsZero() && now.Before(m.From) {
+				r.upcomingUsers[ident] = struct{}{}
+			}
 		}
 	}
 
@@ -47,7 +111,21 @@ func NewGrupin(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn
 		if features, err := newFeatures(yamlPath); err != nil {
 			return r, err
 		} else {
-			r.setWarehouses(semCnf, features.warehouses)
+			if err := r.setWarehouses(semCnf, features.warehouses); err != nil {
+				return r, err
+			}
+			if err := r.setStages(semCnf, features.stages); err != nil {
+				return r, err
+			}
+			if err := r.setResourceMonitors(semCnf, features.resourceMonitors); err != nil {
+				return r, err
+			}
+			if err := r.setBreakGlass(semCnf, features.breakGlass, now); err != nil {
+				return r, err
+			}
+			if err := r.setSessionPolicies(semCnf, cnf, features.sessionPolicies); err != nil {
+				return r, err
+			}
 		}
 	}
 
@@ -55,24 +133,29 @@ func NewGrupin(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn
 }
 
 func (g *Grupin) setWarehouses(semCnf *semantics.Config, warehouses []WarehouseDecoded) error {
-	seen := map[semantics.Ident]struct{}{}
+	g.warehouses = map[semantics.Ident]WarehouseProps{}
 	for _, w := range warehouses {
 		id, err := semantics.NewIdentStripQuotesIfAny(w.Ident, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr)
 		if err != nil {
 			return err
 		}
-		if _, ok := seen[id]; ok {
+		if _, ok := g.warehouses[id]; ok {
 			return fmt.Errorf("duplicate warehouse identifier '%v'", id)
 		}
+		props, err := newWarehouseProps(w)
+		if err != nil {
+			return fmt.Errorf("warehouse '%v': %w", id, err)
+		}
 		mode, err := ParseMode(w.Mode)
 		if err != nil {
 			return fmt.Errorf("warehouse '%v', invalid mode '%v'", id, w.Mode)
 		}
-		if mode != ModeRead && mode != ModeWrite {
-			return fmt.Errorf("warehouse '%v', mode '%v' not implemented", id, mode)
+		if mode == ModeOperate {
+			// Operate roles are granted OPERATE and MONITOR on all warehouses of their product dtap
+			return fmt.Errorf("warehouse '%v', mode '%v' is not a warehouse mode, use '%v' or '%v'", id, mode, ModeRead, ModeWrite)
 		}
 		if w.OnlyProd && w.OnlyNonProd {
-			return fmt.Errorf("warehouse '%v', only_prod and only_non_prod should not both be true", id, mode)
+			return fmt.Errorf("warehouse '%v', only_prod and only_non_prod should not both be true", id)
 		}
 		if mode == ModeWrite && !w.OnlyProd && !w.OnlyNonProd {
 			return fmt.Errorf("warehouse '%v', write mode warehouses should be either for prod or non prod use", id)
@@ -94,11 +177,396 @@ func (g *Grupin) setWarehouses(semCnf *semantics.Config, warehouses []WarehouseD
 				}
 			}
 		}
+		g.warehouses[id] = props
+	}
+	return nil
+}
+
+func (g *Grupin) manageWarehouses(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Create warehouses declared in the YAML that do not exist yet, and bring the properties of existing ones in
+	// line with the YAML. Warehouses that are owned by grupr, but no longer declared, are only dropped if grupr is run
+	// with --drop-warehouses.
+	existing := map[semantics.Ident]struct{}{}
+	for w, err := range QueryWarehouses(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		existing[w.Name] = struct{}{}
+		if declared, ok := g.warehouses[w.Name]; ok {
+			if props := declared.buildSQLProps(w.WarehouseProps); len(props) > 0 {
+				if err := AlterWarehouse(ctx, cnf, conn, w.Name, props); err != nil {
+					return err
+				}
+			}
+		} else if w.Owner == cnf.Role {
+			if cnf.DropWarehouses {
+				if err := DropWarehouse(ctx, cnf, conn, w.Name); err != nil {
+					return err
+				}
+			} else {
+				log.Printf("WARN: warehouse '%v' is owned by grupr, but no longer declared; run grupr with --drop-warehouses to drop it", w.Name)
+			}
+		}
+	}
+	for id, props := range g.warehouses {
+		if _, ok := existing[id]; !ok {
+			if err := CreateWarehouse(ctx, cnf, conn, id, props); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) newUser(name semantics.Ident, isService bool) User {
+	// Service account users deploy, so they would use a write role; people use a read role. We only set defaults if
+	// there is just one product role to choose from, and use the warehouse of that role, if it has only one.
+	u := User{Name: name, IsService: isService}
+	mode := ModeRead
+	if isService {
+		mode = ModeWrite
+	}
+	var candidates []*ProductDTAP
+	for _, pd := range g.ProductDTAPs {
+		if _, ok := pd.getGrantRoleToUsers(mode)[name]; ok {
+			candidates = append(candidates, pd)
+		}
+	}
+	if len(candidates) == 1 {
+		pd := candidates[0]
+		u.DefaultRole = pd.getProductRole(mode).ID
+		if warehouses := pd.getWarehouses(mode); len(warehouses) == 1 {
+			for w := range warehouses {
+				u.DefaultWarehouse = w
+			}
+		}
+	}
+	return u
+}
+
+func (g *Grupin) manageUsers(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Create users that do not exist yet, disable users that are no longer declared, and enable them again if they
+	// are declared again; users outside of the configured scope, and the grupr user itself, are left alone. Users
+	// that grupr did not create, i.e., that are not owned by the grupr role, are not disabled or enabled either.
+	inScope := func(name semantics.Ident) bool {
+		return name != cnf.User && cnf.UserScope.MatchString(string(name))
+	}
+	existing := map[semantics.Ident]struct{}{}
+	for u, err := range queryUsers(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		existing[u.Name] = struct{}{}
+		if !inScope(u.Name) || u.Owner != cnf.Role {
+			continue
+		}
+		if _, upcoming := g.upcomingUsers[u.Name]; upcoming {
+			continue
+		}
+		if _, declared := g.users[u.Name]; declared && u.Disabled {
+			log.Printf("Enabling user '%v'", u.Name)
+			if err := SetUserDisabled(ctx, cnf, conn, u.Name, false); err != nil {
+				return err
+			}
+		} else if !declared && !u.Disabled {
+			log.Printf("Disabling user '%v', it is no longer declared", u.Name)
+			if err := SetUserDisabled(ctx, cnf, conn, u.Name, true); err != nil {
+				return err
+			}
+		}
+	}
+	for name, isService := range g.users {
+		if _, ok := existing[name]; ok || !inScope(name) {
+			continue
+		}
+		if err := g.newUser(name, isService).create(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) setStages(semCnf *semantics.Config, stages []StageDecoded) error {
+	seen := map[ObjectID]struct{}{}
+	for _, s := range stages {
+		var id ObjectID
+		var err error
+		if id.Database, err = semantics.NewIdentStripQuotesIfAny(s.Database, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr); err != nil {
+			return err
+		}
+		if id.Schema, err = semantics.NewIdentStripQuotesIfAny(s.Schema, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr); err != nil {
+			return err
+		}
+		if id.Object, err = semantics.NewIdentStripQuotesIfAny(s.Ident, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s.%s.%s", id.Database, id.Schema, id.Object)
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("duplicate stage identifier '%s'", name)
+		}
+		mode, err := ParseMode(s.Mode)
+		if err != nil {
+			return fmt.Errorf("stage '%s', invalid mode '%v'", name, s.Mode)
+		}
+		if mode != ModeRead && mode != ModeWrite {
+			return fmt.Errorf("stage '%s', mode '%v' not implemented", name, mode)
+		}
+		if s.OnlyProd && s.OnlyNonProd {
+			return fmt.Errorf("stage '%s', only_prod and only_non_prod should not both be true", name)
+		}
+		if mode == ModeWrite && !s.OnlyProd && !s.OnlyNonProd {
+			return fmt.Errorf("stage '%s', write mode stages should be either for prod or non prod use", name)
+		}
+		seenPIDs := map[string]struct{}{}
+		for _, pID := range s.SharedBetween {
+			if _, ok := seenPIDs[pID]; ok {
+				return fmt.Errorf("stage '%s', shared_between: duplicate product id '%v'", name, pID)
+			}
+			if !g.hasProductID(pID) {
+				return fmt.Errorf("stage '%s', shared_between: unknown product id '%v'", name, pID)
+			}
+			seenPIDs[pID] = struct{}{}
+
+			// Okay, all good, add stage to necessary dtaps
+			for pd := range g.getProductDTAPs(pID) {
+				if (pd.IsProd && !s.OnlyNonProd) || (!pd.IsProd && !s.OnlyProd) {
+					pd.addStage(mode, id, s.External)
+				}
+			}
+		}
 		seen[id] = struct{}{}
 	}
 	return nil
 }
 
+func (g *Grupin) setBreakGlass(semCnf *semantics.Config, breakGlass []BreakGlassDecoded, now time.Time) error {
+	for _, bd := range breakGlass {
+		b, err := newBreakGlass(semCnf, bd)
+		if err != nil {
+			return fmt.Errorf("break glass for user '%s', product '%s', dtap '%s': %w", bd.User, bd.ProductID, bd.DTAP, err)
+		}
+		pd, ok := g.ProductDTAPs[b.ProductDTAPID]
+		if !ok {
+			return fmt.Errorf("%v: unknown product dtap", b)
+		}
+		pd.addBreakGlass(b, now)
+		if _, ok := g.users[b.User]; !ok && now.Before(b.ExpiresAt) {
+			g.users[b.User] = false
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) setSessionPolicies(semCnf *semantics.Config, cnf *Config, sessionPolicies []SessionPolicyDecoded) error {
+	g.sessionPolicies = map[semantics.Ident]*SessionPolicy{}
+	policyOfUser := map[semantics.Ident]semantics.Ident{}
+	for _, decoded := range sessionPolicies {
+		p, err := newSessionPolicy(semCnf, decoded)
+		if err != nil {
+			return fmt.Errorf("session policy '%s': %w", decoded.Name, err)
+		}
+		if _, ok := g.sessionPolicies[p.Name]; ok {
+			return fmt.Errorf("session policy: duplicate name '%s'", decoded.Name)
+		}
+		// A user can only have one session policy
+		for u := range p.Users {
+			if u == cnf.User {
+				return fmt.Errorf("session policy '%s': the grupr user '%v' gets its own session policy", decoded.Name, u)
+			}
+			if other, ok := policyOfUser[u]; ok {
+				return fmt.Errorf("session policy: user '%v' would get both session policy '%v' and '%v'", u, other, p.Name)
+			}
+			policyOfUser[u] = p.Name
+			if _, ok := g.users[u]; !ok {
+				g.users[u] = false
+			}
+		}
+		g.sessionPolicies[p.Name] = p
+	}
+	return nil
+}
+
+func (g *Grupin) manageSessionPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	// Next to the declared session policies, there is a default one for the account, and one for the grupr user
+	policies := map[semantics.Ident]*SessionPolicy{}
+	for k, v := range g.sessionPolicies {
+		policies[k] = v
+	}
+	accountPolicy := &SessionPolicy{
+		Name:                  newSessionPolicyName(semCnf, sessionPolicyDefault),
+		AllowedSecondaryRoles: []string{},
+		Users:                 map[semantics.Ident]struct{}{},
+	}
+	policies[accountPolicy.Name] = accountPolicy
+	gruprPolicy := &SessionPolicy{
+		Name:                  newSessionPolicyName(semCnf, sessionPolicyGrupr),
+		AllowedSecondaryRoles: []string{"ALL"},
+		Users:                 map[semantics.Ident]struct{}{cnf.User: {}},
+	}
+	policies[gruprPolicy.Name] = gruprPolicy
+
+	// Create or alter them
+	existing := map[semantics.Ident]struct{}{}
+	for name, err := range querySessionPolicies(ctx, cnf, conn) {
+		if err != nil {
+			return err
+		}
+		existing[name] = struct{}{}
+	}
+	for name, p := range policies {
+		if _, ok := existing[name]; ok {
+			if err := p.alterIfNeeded(ctx, cnf, conn); err != nil {
+				return err
+			}
+		} else if err := p.create(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+
+	// Attach them
+	account, err := queryCurrentAccount(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := attachSessionPolicy(ctx, semCnf, cnf, conn, accountPolicy, ObjTpAccount, account); err != nil {
+		return err
+	}
+	for _, p := range policies {
+		for u := range p.Users {
+			if err := attachSessionPolicy(ctx, semCnf, cnf, conn, p, ObjTpUser, string(u)); err == ErrObjectNotExistOrAuthorized {
+				// Like with grants, one user that does not exist should not stop us from managing the rest
+				log.Printf("WARN: could not attach session policy '%v' to user '%v', it does not exist, or grupr is not authorized", p.Name, u)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Detach session policies managed by grupr from users that are no longer declared, and drop the session
+	// policies that are no longer declared
+	for name := range existing {
+		if !strings.HasPrefix(string(name), string(newSessionPolicyName(semCnf, ""))) {
+			continue
+		}
+		p, declared := policies[name]
+		for u, err := range queryUsersOfSessionPolicy(ctx, cnf, conn, name) {
+			if err != nil {
+				return err
+			}
+			if declared {
+				if _, ok := p.Users[u]; ok {
+					continue
+				}
+			}
+			if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER USER IDENTIFIER($$%s$$) UNSET SESSION POLICY`, u)); err != nil {
+				return err
+			}
+		}
+		if !declared {
+			if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`DROP SESSION POLICY IF EXISTS %s.%s.%s`, cnf.Database, cnf.Schema, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) setResourceMonitors(semCnf *semantics.Config, resourceMonitors []ResourceMonitorDecoded) error {
+	// Resource monitors are attached to the write warehouses of a product dtap, or of all dtaps of a product; since a
+	// warehouse can have only one resource monitor, we make sure no warehouse ends up with more than one
+	g.resourceMonitors = map[semantics.Ident]*ResourceMonitor{}
+	monitorOfWarehouse := map[semantics.Ident]semantics.Ident{}
+	dtapOfProduct := map[string]string{} // empty dtap: quota for the product as a whole
+	for _, decoded := range resourceMonitors {
+		rm, err := newResourceMonitor(semCnf, decoded)
+		if err != nil {
+			return fmt.Errorf("resource monitor for product '%s', dtap '%s': %w", decoded.ProductID, decoded.DTAP, err)
+		}
+		if !g.hasProductID(rm.ProductID) {
+			return fmt.Errorf("resource monitor: unknown product id '%s'", rm.ProductID)
+		}
+		if rm.DTAP != "" {
+			if _, ok := g.ProductDTAPs[semantics.ProductDTAPID{ProductID: rm.ProductID, DTAP: rm.DTAP}]; !ok {
+				return fmt.Errorf("resource monitor: unknown dtap '%s' for product id '%s'", rm.DTAP, rm.ProductID)
+			}
+		}
+		if _, ok := g.resourceMonitors[rm.Name]; ok {
+			return fmt.Errorf("resource monitor: duplicate for product '%s', dtap '%s'", rm.ProductID, rm.DTAP)
+		}
+		if prevDTAP, ok := dtapOfProduct[rm.ProductID]; ok && (prevDTAP == "" || rm.DTAP == "") {
+			return fmt.Errorf("resource monitor: product '%s' has a quota both for the product and for a dtap", rm.ProductID)
+		}
+		dtapOfProduct[rm.ProductID] = rm.DTAP
+		for pd := range g.getProductDTAPs(rm.ProductID) {
+			if rm.DTAP != "" && pd.DTAP != rm.DTAP {
+				continue
+			}
+			for w := range pd.WriteWarehouses {
+				if other, ok := monitorOfWarehouse[w]; ok && other != rm.Name {
+					return fmt.Errorf("resource monitor: warehouse '%v' would get both resource monitor '%v' and '%v'", w, other, rm.Name)
+				}
+				monitorOfWarehouse[w] = rm.Name
+				rm.Warehouses[w] = struct{}{}
+			}
+		}
+		g.resourceMonitors[rm.Name] = rm
+	}
+	return nil
+}
+
+func (g *Grupin) manageResourceMonitors(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	// Create or alter declared resource monitors; drop resource monitors that grupr created but that are no longer
+	// declared. Resource monitors created by others are left alone.
+	existing := map[semantics.Ident]struct{}{}
+	for s, err := range queryResourceMonitors(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		existing[s.Name] = struct{}{}
+		if rm, ok := g.resourceMonitors[s.Name]; ok {
+			if err := rm.alter(ctx, cnf, conn, s); err != nil {
+				return err
+			}
+		} else if s.Owner == cnf.Role && strings.HasPrefix(string(s.Name), string(semCnf.Prefix)) {
+			// Detach it first; dropping a resource monitor that is attached to a warehouse fails
+			for w, err := range QueryWarehouses(ctx, conn) {
+				if err != nil {
+					return err
+				}
+				if w.ResourceMonitor == s.Name {
+					if err := SetWarehouseResourceMonitor(ctx, cnf, conn, w.Name, ""); err != nil {
+						return err
+					}
+				}
+			}
+			if err := DropResourceMonitor(ctx, cnf, conn, s.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for name, rm := range g.resourceMonitors {
+		if _, ok := existing[name]; !ok {
+			if err := rm.create(ctx, cnf, conn); err != nil {
+				return err
+			}
+		}
+	}
+	// Attach monitors to warehouses
+	for w, err := range QueryWarehouses(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		for name, rm := range g.resourceMonitors {
+			if _, ok := rm.Warehouses[w.Name]; ok && w.ResourceMonitor != name {
+				if err := SetWarehouseResourceMonitor(ctx, cnf, conn, w.Name, name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (g *Grupin) hasProductID(pID string) bool {
 	for pdID := range g.ProductDTAPs {
 		if pdID.ProductID == pID {
@@ -189,6 +657,54 @@ func (g *Grupin) ManageAccess(ctx context.Context, semCnf *semantics.Config, cnf
 	// We will add them as non-prod, so they'll be dealt with after production.
 	g.addZombieProductDTAPs()
 
+	// Find the masking and row access policies that grupr created earlier, and hand them to the product dtaps they belong to
+	if err := g.setMaskingPolicies(ctx, semCnf, cnf, conn); err != nil {
+		return err
+	}
+	if err := g.setRowAccessPolicies(ctx, semCnf, cnf, conn); err != nil {
+		return err
+	}
+	// Resume pipes and tasks that an earlier run suspended to transfer their ownership, if it crashed before resuming them
+	if err := CreateSuspendedObjectsTable(ctx, cnf, conn); err != nil {
+		return err
+	}
+	if err := ResumeSuspendedObjects(ctx, cnf, conn); err != nil {
+		return err
+	}
+	// Break glass grants are recorded in a table
+	if err := CreateBreakGlassLogTable(ctx, cnf, conn); err != nil {
+		return err
+	}
+	// As are grants of write roles to legacy owners of objects, so that we can revoke them after a grace period
+	if err := CreateLegacyOwnerGrantsTable(ctx, cnf, conn); err != nil {
+		return err
+	}
+	if err := g.setLegacyOwnerGrants(ctx, cnf, conn); err != nil {
+		return err
+	}
+	// Warehouses should exist before we grant privileges on them
+	if g.warehouses != nil {
+		if err := g.manageWarehouses(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+	// And resource monitors should exist before we attach them to warehouses
+	if g.resourceMonitors != nil {
+		if err := g.manageResourceMonitors(ctx, semCnf, cnf, conn); err != nil {
+			return err
+		}
+	}
+	// Make sure there are salts for masking policies that hash values
+	if err := g.createHashSalts(ctx, cnf, conn); err != nil {
+		return err
+	}
+	// And tags, if we manage them
+	if cnf.ManageTags {
+		if err := CreateTags(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+
 	// Now, set up product roles for all products; prod or non-prod. Because zombie product dtaps
 	// may or may not be production, we have no way of knowing that. And, when we claim objects
 	// from such zombie product dtaps, any user managed roles that were granted the write role
@@ -206,6 +722,20 @@ func (g *Grupin) ManageAccess(ctx context.Context, semCnf *semantics.Config, cnf
 		return err
 	}
 
+	// Users should exist before we grant product roles to them, if we provision them
+	if cnf.ProvisionUsers {
+		if err := g.manageUsers(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+
+	// Session policies, which are attached to users, so after provisioning them
+	if cnf.ManageSessionPolicies {
+		if err := g.manageSessionPolicies(ctx, semCnf, cnf, conn); err != nil {
+			return err
+		}
+	}
+
 	// Now, first complete production
 	if err := g.manageAccess(ctx, semCnf, cnf, conn, true); err != nil {
 		return err
@@ -227,7 +757,12 @@ func (g *Grupin) ManageAccess(ctx context.Context, semCnf *semantics.Config, cnf
 	if err := g.dropDatabaseRoles(ctx, cnf, conn); err != nil {
 		return err
 	}
-	return nil
+
+	// And masking and row access policies that are no longer attached by a product dtap
+	if err := g.dropMaskingPolicies(ctx, cnf, conn); err != nil {
+		return err
+	}
+	return g.dropRowAccessPolicies(ctx, cnf, conn)
 }
 
 func (g *Grupin) setDBRoleGrants(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, pd *ProductDTAP) error {
@@ -359,6 +894,27 @@ func (g *Grupin) DisjointFromObject(db semantics.Ident, schema semantics.Ident,
 	return true
 }
 
+func (g *Grupin) TagsObject(id ObjectID) bool {
+	// Mirrors which databases, schemas, and objects product dtaps tag, see ProductDTAP.getToDoTagRefs
+	for _, pd := range g.ProductDTAPs {
+		switch {
+		case id.Schema == "":
+			if pd.Interface.ObjectMatchers.MatchAllSchemasInDB(id.Database) {
+				return true
+			}
+		case id.Object == "":
+			if pd.Interface.ObjectMatchers.MatchAllObjectsInSchema(id.Database, id.Schema) {
+				return true
+			}
+		default:
+			if !pd.Interface.ObjectMatchers.DisjointFromObject(id.Database, id.Schema, id.Object) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (g *Grupin) grant(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, doProd bool) error {
 	// The bulk of the grants are granting objects to roles, we do it concurrently per product-dtap
 	eg, egCtx := errgroup.WithContext(ctx)
@@ -404,6 +960,9 @@ func (g *Grupin) grant(ctx context.Context, semCnf *semantics.Config, cnf *Confi
 			if err := pd.setGrantedUsers(ctx, cnf, conn, g.productRoles); err != nil {
 				return err
 			}
+			if err := pd.recordBreakGlass(ctx, cnf, conn); err != nil {
+				return err
+			}
 		}
 	}
 	// Do the todo grants of product dtap roles to users
@@ -422,13 +981,26 @@ func (g *Grupin) revoke(ctx context.Context, semCnf *semantics.Config, cnf *Conf
 					},
 					func(pdID semantics.ProductDTAPID) map[semantics.Ident]struct{} {
 						return g.ProductDTAPs[pdID].writeRoleGrantedToUserManagedRoles
-					}, g.accountCache)
+					}, g.TagsObject, g.accountCache)
 			})
 		}
 	}
 	return eg.Wait()
 }
 
+func (g *Grupin) setLegacyOwnerGrants(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	m, err := QueryLegacyOwnerGrants(ctx, cnf, conn)
+	if err != nil {
+		return err
+	}
+	for pdID, pd := range g.ProductDTAPs {
+		if pd.legacyOwnerGrants = m[pdID]; pd.legacyOwnerGrants == nil {
+			pd.legacyOwnerGrants = map[semantics.Ident]time.Time{}
+		}
+	}
+	return nil
+}
+
 func (g *Grupin) setProductRoles(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
 	g.productRoles = map[ProductRole]struct{}{}
 	// TODO: move query to product_role.go, working in similar way like grant.go or obj.go
@@ -451,6 +1023,75 @@ func (g *Grupin) setProductRoles(ctx context.Context, semCnf *semantics.Config,
 	return rows.Err()
 }
 
+func (g *Grupin) setMaskingPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	g.maskingPolicies = map[semantics.Ident]MaskingPolicy{}
+	for p, err := range QueryMaskingPolicies(ctx, semCnf, cnf, conn) {
+		if err != nil {
+			return err
+		}
+		g.maskingPolicies[p.Name] = p
+		if pd, ok := g.ProductDTAPs[p.ProductDTAPID()]; ok {
+			pd.existingMaskingPolicies[p.Name] = p
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) setRowAccessPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	g.rowAccessPolicies = map[semantics.Ident]RowAccessPolicy{}
+	for p, err := range QueryRowAccessPolicies(ctx, semCnf, cnf, conn) {
+		if err != nil {
+			return err
+		}
+		g.rowAccessPolicies[p.Name] = p
+		if pd, ok := g.ProductDTAPs[p.ProductDTAPID()]; ok {
+			pd.existingRowAccessPolicies[p.Name] = p
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) dropRowAccessPolicies(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	for name, p := range g.rowAccessPolicies {
+		if pd, ok := g.ProductDTAPs[p.ProductDTAPID()]; ok {
+			if _, ok := pd.rowAccessPolicies[name]; ok {
+				continue // this policy is still needed
+			}
+		}
+		if err := p.Drop(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Grupin) createHashSalts(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	scopes := map[string]struct{}{}
+	for _, pd := range g.ProductDTAPs {
+		if pd.hasHashColumns() {
+			scopes[hashSaltScope(cnf, pd.ProductID)] = struct{}{}
+		}
+	}
+	if len(scopes) == 0 {
+		return nil
+	}
+	return CreateHashSalts(ctx, cnf, conn, scopes)
+}
+
+func (g *Grupin) dropMaskingPolicies(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	for name, p := range g.maskingPolicies {
+		if pd, ok := g.ProductDTAPs[p.ProductDTAPID()]; ok {
+			if _, ok := pd.maskingPolicies[name]; ok {
+				continue // this policy is still needed
+			}
+		}
+		if err := p.Drop(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (g *Grupin) dropDatabaseRoles(ctx context.Context, cnf *Config, conn *sql.DB) error {
 	for db, dbCache := range g.accountCache.getDBs() {
 		for r := range dbCache.dbRoles {
@@ -0,0 +1,48 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Hashing policies read their salt from a table in the schema configured for grupr. The table is owned by the
+// grupr role, and masking policies are evaluated with the privileges of their owner, so consumers do not need,
+// and should not get, access to this table.
+//
+// With GRUPR_SNOWFLAKE_HASH_SALT_SCOPE=account (the default), all products share a single salt, and hashed
+// columns can be joined across products. With GRUPR_SNOWFLAKE_HASH_SALT_SCOPE=product, each product has its own
+// salt, and hashed columns can only be joined across interfaces of the same product.
+
+func hashSaltScope(cnf *Config, productID string) string {
+	if cnf.HashSaltScope == "product" {
+		return productID
+	}
+	return ""
+}
+
+func hashSaltExpr(cnf *Config, productID string) string {
+	return fmt.Sprintf(`(SELECT salt FROM %v.%v.hash_salts WHERE scope = $$%s$$)`, cnf.Database, cnf.Schema, hashSaltScope(cnf, productID))
+}
+
+func CreateHashSalts(ctx context.Context, cnf *Config, conn *sql.DB, scopes map[string]struct{}) error {
+	if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v.%v.hash_salts (
+	scope varchar,
+	salt varchar
+)
+`, cnf.Database, cnf.Schema)); err != nil {
+		return fmt.Errorf("create table: %v", err)
+	}
+	for scope := range scopes {
+		// Salts are never changed once created; that would break joins on hashed values that consumers may have stored
+		if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`
+INSERT INTO %v.%v.hash_salts (scope, salt)
+SELECT $$%s$$, UUID_STRING()
+WHERE NOT EXISTS (SELECT 1 FROM %v.%v.hash_salts WHERE scope = $$%s$$)
+`, cnf.Database, cnf.Schema, scope, cnf.Database, cnf.Schema, scope)); err != nil {
+			return fmt.Errorf("insert salt: %v", err)
+		}
+	}
+	return nil
+}
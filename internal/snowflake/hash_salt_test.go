@@ -0,0 +1,84 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestHashedValue(t *testing.T) {
+	tests := []struct {
+		scope    string
+		dataType string
+		want     string
+	}{
+		{
+			scope:    "account",
+			dataType: "VARCHAR",
+			want:     `SHA2(CONCAT((SELECT salt FROM "GRUPR_DB"."GRUPR".hash_salts WHERE scope = $$$$), val))`,
+		},
+		{
+			scope:    "account",
+			dataType: "NUMBER",
+			want:     `HASH((SELECT salt FROM "GRUPR_DB"."GRUPR".hash_salts WHERE scope = $$$$), val)`,
+		},
+		{
+			scope:    "product",
+			dataType: "VARCHAR",
+			want:     `SHA2(CONCAT((SELECT salt FROM "GRUPR_DB"."GRUPR".hash_salts WHERE scope = $$crm$$), val))`,
+		},
+	}
+	for _, test := range tests {
+		cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR", HashSaltScope: test.scope}
+		if got := hashedValue(cnf, "crm", test.dataType); got != test.want {
+			t.Errorf("hashedValue(%s, %s) = %q, want %q", test.scope, test.dataType, got, test.want)
+		}
+	}
+}
+
+func TestCanHash(t *testing.T) {
+	tests := []struct {
+		dataType string
+		want     bool
+	}{
+		{dataType: "VARCHAR", want: true},
+		{dataType: "NUMBER", want: true},
+		{dataType: "DATE", want: false},
+		{dataType: "FLOAT", want: false},
+	}
+	for _, test := range tests {
+		if got := canHash(test.dataType); got != test.want {
+			t.Errorf("canHash(%s) = %v, want %v", test.dataType, got, test.want)
+		}
+	}
+}
+
+func TestNewMaskingPolicyHashing(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR", HashSaltScope: "account"}
+	pdID := semantics.ProductDTAPID{ProductID: "crm", DTAP: "p"}
+	a := semantics.ProductDTAPID{ProductID: "a", DTAP: "p"}
+	b := semantics.ProductDTAPID{ProductID: "b", DTAP: "p"}
+	// a sees masked values in one interface, and hashed values in another: masking wins
+	p := newMaskingPolicy(semCnf, cnf, pdID, "NUMBER", map[semantics.ProductDTAPID]struct{}{a: {}},
+		map[semantics.ProductDTAPID]struct{}{a: {}, b: {}})
+	want := `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN val
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN NULL
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN HASH((SELECT salt FROM "GRUPR_DB"."GRUPR".hash_salts WHERE scope = $$$$), val)
+  WHEN IS_ROLE_IN_SESSION($$_X_B_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_B_X_P_X_W$$) THEN HASH((SELECT salt FROM "GRUPR_DB"."GRUPR".hash_salts WHERE scope = $$$$), val)
+  ELSE val
+END`
+	if p.body != want {
+		t.Errorf("body = %q, want %q", p.body, want)
+	}
+	// The salt scope is part of the body, so changing it gives a policy with a different name
+	cnf.HashSaltScope = "product"
+	if q := newMaskingPolicy(semCnf, cnf, pdID, "NUMBER", map[semantics.ProductDTAPID]struct{}{a: {}},
+		map[semantics.ProductDTAPID]struct{}{a: {}, b: {}}); q.Name == p.Name {
+		t.Errorf("name %v does not depend on the hash salt scope", p.Name)
+	}
+}
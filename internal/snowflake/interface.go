@@ -8,13 +8,17 @@ import (
 	"strings"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
+	"github.com/rwberendsen/grupr/internal/syntax"
 )
 
 type Interface struct {
 	ObjectMatchers   semantics.ObjMatchers
+	Classification   semantics.Classification
 	GlobalUserGroups map[string]struct{}
 	UserGroupMapping semantics.UserGroupMapping
 	ConsumedBy       map[semantics.ProductDTAPID]struct{}
+	MaskColumns      semantics.ColExprs
+	HashColumns      semantics.ColExprs
 
 	// Granular accountObjects by ObjExpr; will be discarded after aggregate() is called
 	accountObjects map[semantics.ObjExpr]AccountObjs
@@ -25,14 +29,24 @@ type Interface struct {
 	// Computed by aggregate()
 	aggAccountObjects AggAccountObjs
 
-	// For use in pushObjectCounts
+	// Global user groups of the objects matched by the interface, by database (Schema and Object empty), schema
+	// (Object empty), and object; computed by setUserGroupsByObject, only for the product-level interface, and only
+	// if we manage tags
+	userGroupsByObject map[ObjectID]map[string]struct{} // "" means shared by usergroups of interface, if any
+
+	// For use in pushObjectCounts and tags
 	globalUserGroupsStr string
+	classificationStr   string
 }
 
-func NewInterface(dtap string, iSem semantics.InterfaceMetadata, um semantics.UserGroupMapping) *Interface {
+func NewInterface(dtap string, iSem semantics.InterfaceMetadata, um semantics.UserGroupMapping, classes map[string]syntax.Class) *Interface {
 	i := &Interface{
-		ObjectMatchers:   semantics.ObjMatchers{},
-		UserGroupMapping: um,
+		ObjectMatchers:    semantics.ObjMatchers{},
+		Classification:    iSem.Classification,
+		classificationStr: classificationLabel(classes, iSem.Classification),
+		UserGroupMapping:  um,
+		MaskColumns:       semantics.ColExprs{},
+		HashColumns:       semantics.ColExprs{},
 	}
 	// Just take what you need from own DTAP
 	for e, om := range iSem.ObjectMatchers {
@@ -40,6 +54,16 @@ func NewInterface(dtap string, iSem semantics.InterfaceMetadata, um semantics.Us
 			i.ObjectMatchers[e] = om
 		}
 	}
+	for e, ea := range iSem.MaskColumns.ColExprs {
+		if ea.DTAP == "" || ea.DTAP == dtap {
+			i.MaskColumns[e] = ea
+		}
+	}
+	for e, ea := range iSem.HashColumns.ColExprs {
+		if ea.DTAP == "" || ea.DTAP == dtap {
+			i.HashColumns[e] = ea
+		}
+	}
 	// Set Global user groups and userGroupStr
 	if iSem.UserGroups != nil {
 		i.GlobalUserGroups = map[string]struct{}{}
@@ -62,6 +86,16 @@ func NewInterface(dtap string, iSem semantics.InterfaceMetadata, um semantics.Us
 	return i
 }
 
+func classificationLabel(classes map[string]syntax.Class, c semantics.Classification) string {
+	// Several labels could have the same level; pick the first one, so that we are deterministic
+	for _, k := range slices.Sorted(maps.Keys(classes)) {
+		if semantics.Classification(classes[k].Level) == c {
+			return k
+		}
+	}
+	return ""
+}
+
 func (i *Interface) recalcObjectsFromMatched(m map[semantics.ObjExpr]*matchedAccountObjs) {
 	// Called from the product level only
 	i.accountObjects = map[semantics.ObjExpr]AccountObjs{} // (re)set
@@ -94,11 +128,49 @@ func (i *Interface) setCountsByUserGroup() {
 		if i.objectCountsByUserGroup[globalUserGroup] == nil {
 			i.objectCountsByUserGroup[globalUserGroup] = map[ObjType]int{}
 		}
-		i.objectCountsByUserGroup[globalUserGroup][ObjTpTable] += i.accountObjects[e].countByObjType(ObjTpTable)
-		i.objectCountsByUserGroup[globalUserGroup][ObjTpView] += i.accountObjects[e].countByObjType(ObjTpView)
+		for _, ot := range objTypesObjectLevel {
+			i.objectCountsByUserGroup[globalUserGroup][ot] += i.accountObjects[e].countByObjType(ot)
+		}
 	}
 }
 
+func (i *Interface) setUserGroupsByObject() {
+	i.userGroupsByObject = map[ObjectID]map[string]struct{}{}
+	add := func(id ObjectID, ug string) {
+		if i.userGroupsByObject[id] == nil {
+			i.userGroupsByObject[id] = map[string]struct{}{}
+		}
+		i.userGroupsByObject[id][ug] = struct{}{}
+	}
+	for e, om := range i.ObjectMatchers {
+		var globalUserGroup string
+		if om.UserGroup != "" {
+			globalUserGroup = i.UserGroupMapping[om.UserGroup]
+		}
+		for db, dbObjs := range i.accountObjects[e].DBs {
+			if dbObjs.MatchAllSchemas {
+				add(ObjectID{Database: db}, globalUserGroup)
+			}
+			for schema, schemaObjs := range dbObjs.Schemas {
+				if schemaObjs.MatchAllObjects {
+					add(ObjectID{Database: db, Schema: schema}, globalUserGroup)
+				}
+				for obj := range schemaObjs.Objects {
+					add(ObjectID{Database: db, Schema: schema, Object: obj}, globalUserGroup)
+				}
+			}
+		}
+	}
+}
+
+func (i *Interface) getUserGroupsStr(id ObjectID) string {
+	ugs := i.userGroupsByObject[id]
+	if _, ok := ugs[""]; ok || len(ugs) == 0 {
+		return i.globalUserGroupsStr
+	}
+	return strings.Join(slices.Sorted(maps.Keys(ugs)), ",")
+}
+
 func (i *Interface) setAggAccountObjects() {
 	sum := AccountObjs{}
 	for _, o := range i.accountObjects {
@@ -205,6 +277,18 @@ func (i *Interface) pushObjectCounts(yield func(ObjCountsRow) bool, pdID semanti
 			UserGroups:  ug,
 			TableCount:  countsByObjType[ObjTpTable],
 			ViewCount:   countsByObjType[ObjTpView],
+
+			MaterializedViewCount: countsByObjType[ObjTpMaterializedView],
+			DynamicTableCount:     countsByObjType[ObjTpDynamicTable],
+			ExternalTableCount:    countsByObjType[ObjTpExternalTable],
+			IcebergTableCount:     countsByObjType[ObjTpIcebergTable],
+			EventTableCount:       countsByObjType[ObjTpEventTable],
+			FunctionCount:         countsByObjType[ObjTpFunction],
+			ProcedureCount:        countsByObjType[ObjTpProcedure],
+			SequenceCount:         countsByObjType[ObjTpSequence],
+			PipeCount:             countsByObjType[ObjTpPipe],
+			TaskCount:             countsByObjType[ObjTpTask],
+			StreamCount:           countsByObjType[ObjTpStream],
 		}
 		if ug == "" {
 			r.UserGroups = i.globalUserGroupsStr
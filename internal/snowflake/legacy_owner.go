@@ -0,0 +1,179 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// Before grupr claims ownership of objects, it grants the product write role to the user managed roles that owned
+// them, so that they do not lose any privileges; these are the legacy owners. Grupr records when it made (or first
+// found) each such grant in the legacy_owner_grants table in the schema configured for grupr. With confiscate_after
+// in the product YAML, or else the --confiscate-after command line flag, grupr revokes the write role from legacy
+// owners after that many days, unless the legacy owner has recently created objects in the product dtap.
+
+func CreateLegacyOwnerGrantsTable(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s.legacy_owner_grants (
+	product_id varchar,
+	dtap varchar,
+	role_name varchar,
+	granted_at timestamp_ltz
+)`, cnf.Database, cnf.Schema))
+}
+
+func QueryLegacyOwnerGrants(ctx context.Context, cnf *Config, conn *sql.DB) (map[semantics.ProductDTAPID]map[semantics.Ident]time.Time, error) {
+	m := map[semantics.ProductDTAPID]map[semantics.Ident]time.Time{}
+	if cnf.DryRun {
+		// In dry run mode, we did not really create the table, so it may not exist yet
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`DESCRIBE TABLE %s.%s.legacy_owner_grants`, cnf.Database, cnf.Schema)); err != nil {
+			return m, nil
+		}
+	}
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT product_id, dtap, role_name, MIN(granted_at)
+FROM %s.%s.legacy_owner_grants
+GROUP BY ALL`, cnf.Database, cnf.Schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pdID semantics.ProductDTAPID
+		var role semantics.Ident
+		var grantedAt time.Time
+		if err = rows.Scan(&pdID.ProductID, &pdID.DTAP, &role, &grantedAt); err != nil {
+			return nil, fmt.Errorf("QueryLegacyOwnerGrants: error scanning row: %w", err)
+		}
+		if _, ok := m[pdID]; !ok {
+			m[pdID] = map[semantics.Ident]time.Time{}
+		}
+		m[pdID][role] = grantedAt
+	}
+	return m, rows.Err()
+}
+
+func recordLegacyOwnerGrant(ctx context.Context, cnf *Config, conn *sql.DB, pdID semantics.ProductDTAPID, role semantics.Ident) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`INSERT INTO %s.%s.legacy_owner_grants (product_id, dtap, role_name, granted_at)
+SELECT ?, ?, ?, CURRENT_TIMESTAMP()`, cnf.Database, cnf.Schema), pdID.ProductID, pdID.DTAP, string(role))
+}
+
+func deleteLegacyOwnerGrant(ctx context.Context, cnf *Config, conn *sql.DB, pdID semantics.ProductDTAPID, role semantics.Ident) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`DELETE FROM %s.%s.legacy_owner_grants
+WHERE product_id = ? AND dtap = ? AND role_name = ?`, cnf.Database, cnf.Schema), pdID.ProductID, pdID.DTAP, string(role))
+}
+
+// queryCountCreatesByRole counts the successful CREATE statements that role ran during the given period up to now, in
+// sessions that used one of the given databases, or schemas; an empty schema means the whole database. Note that
+// query history knows the database and schema of the session, not of the objects created; a legacy owner that uses
+// fully qualified names from a session in another database is not caught here.
+//
+// We compute the start of the period in SQL: a bound time.Time would be a TIMESTAMP_NTZ, which would be compared
+// with start_time, a TIMESTAMP_LTZ, in the time zone of the session.
+func queryCountCreatesByRole(ctx context.Context, conn *sql.DB, role semantics.Ident, period time.Duration,
+	schemas map[semantics.Ident]map[semantics.Ident]struct{}) (int, error) {
+	conds := []string{}
+	params := []any{string(role), -int64(period.Seconds())}
+	for db, m := range schemas {
+		if len(m) == 0 {
+			conds = append(conds, `database_name = ?`)
+			params = append(params, string(db))
+			continue
+		}
+		for schema := range m {
+			conds = append(conds, `(database_name = ? AND schema_name = ?)`)
+			params = append(params, string(db), string(schema))
+		}
+	}
+	if len(conds) == 0 {
+		return 0, nil
+	}
+	var n int
+	err := conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*)
+FROM SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY
+WHERE role_name = ?
+  AND start_time >= DATEADD(second, ?, CURRENT_TIMESTAMP())
+  AND query_type LIKE 'CREATE%%'
+  AND execution_status = 'SUCCESS'
+  AND (%s)`, strings.Join(conds, ` OR `)), params...).Scan(&n)
+	return n, err
+}
+
+func (pd *ProductDTAP) getSchemas() map[semantics.Ident]map[semantics.Ident]struct{} {
+	m := map[semantics.Ident]map[semantics.Ident]struct{}{}
+	for db, dbObjs := range pd.Interface.aggAccountObjects.DBs {
+		m[db] = map[semantics.Ident]struct{}{}
+		if dbObjs.MatchAllSchemas {
+			continue // the whole database
+		}
+		for schema := range dbObjs.Schemas {
+			m[db][schema] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (pd *ProductDTAP) getConfiscateAfter(cnf *Config) time.Duration {
+	if pd.ConfiscateAfter != nil {
+		return *pd.ConfiscateAfter
+	}
+	return cnf.ConfiscateAfter
+}
+
+// getLegacyOwnersDue returns the legacy owners that were granted the write role before since, sorted; grants made
+// during this run are never due.
+func (pd *ProductDTAP) getLegacyOwnersDue(since time.Time) []semantics.Ident {
+	roles := []semantics.Ident{}
+	for role, grantedAt := range pd.legacyOwnerGrants {
+		if _, ok := pd.writeRoleGrantedToUserManagedRoles[role]; !ok {
+			continue // granted during this run
+		}
+		if grantedAt.After(since) {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// confiscate revokes the write role from legacy owners that were granted it longer than the grace period ago, and
+// that did not create objects in the product dtap during the grace period.
+//
+// We do not update writeRoleGrantedToUserManagedRoles here: other product dtaps may be reading it concurrently, and
+// it was set once, before we claimed ownership; the next run of grupr will see the role was revoked.
+func (pd *ProductDTAP) confiscate(ctx context.Context, cnf *Config, conn *sql.DB, now time.Time) error {
+	confiscateAfter := pd.getConfiscateAfter(cnf)
+	if confiscateAfter == 0 || pd.isZombie {
+		return nil
+	}
+	since := now.Add(-confiscateAfter)
+	for _, role := range pd.getLegacyOwnersDue(since) {
+		if n, err := queryCountCreatesByRole(ctx, conn, role, confiscateAfter, pd.getSchemas()); err != nil {
+			return err
+		} else if n > 0 {
+			log.Printf("WARN: product '%s', dtap '%s': legacy owner '%v' created %d objects since %s, not revoking write role",
+				pd.ProductID, pd.DTAP, role, n, since.Format(time.DateOnly))
+			continue
+		}
+		log.Printf("Product '%s', dtap '%s': revoking write role from legacy owner '%v', granted on %s",
+			pd.ProductID, pd.DTAP, role, pd.legacyOwnerGrants[role].Format(time.DateOnly))
+		if err := runSQL(ctx, cnf, conn, Grant{
+			Privileges:    []PrivilegeComplete{PrivilegeComplete{Privilege: PrvUsage}},
+			GrantedOn:     ObjTpRole,
+			GrantedRole:   pd.WriteRole.ID,
+			GrantedTo:     ObjTpRole,
+			GrantedToName: role,
+		}.buildSQLGrant(true)); err != nil {
+			return err
+		}
+		if err := deleteLegacyOwnerGrant(ctx, cnf, conn, pd.ProductDTAPID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package snowflake
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestGetConfiscateAfter(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	never := time.Duration(0)
+	tests := []struct {
+		product *time.Duration
+		flag    time.Duration
+		want    time.Duration
+	}{
+		{product: nil, flag: 0, want: 0},
+		{product: nil, flag: week, want: week},
+		{product: &week, flag: 0, want: week},
+		{product: &never, flag: week, want: 0}, // the product opts out
+	}
+	for i, test := range tests {
+		pd := &ProductDTAP{ConfiscateAfter: test.product}
+		if got := pd.getConfiscateAfter(&Config{ConfiscateAfter: test.flag}); got != test.want {
+			t.Errorf("test %d: getConfiscateAfter() = %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestGetLegacyOwnersDue(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-30 * 24 * time.Hour)
+	pd := &ProductDTAP{
+		legacyOwnerGrants: map[semantics.Ident]time.Time{
+			"OLD":      since.Add(-time.Hour),
+			"EXACT":    since,
+			"RECENT":   since.Add(time.Hour),
+			"THIS_RUN": since.Add(-time.Hour), // recorded earlier, but not found granted at the start of this run
+		},
+		writeRoleGrantedToUserManagedRoles: map[semantics.Ident]struct{}{
+			"OLD":    {},
+			"EXACT":  {},
+			"RECENT": {},
+		},
+	}
+	want := []semantics.Ident{"EXACT", "OLD"}
+	if got := pd.getLegacyOwnersDue(since); !slices.Equal(got, want) {
+		t.Errorf("getLegacyOwnersDue(%v) = %v, want %v", since, got, want)
+	}
+	if got := pd.getLegacyOwnersDue(since.Add(-2 * time.Hour)); len(got) != 0 {
+		t.Errorf("getLegacyOwnersDue() = %v, want none", got)
+	}
+}
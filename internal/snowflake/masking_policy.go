@@ -0,0 +1,194 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// Masking policies are created in the schema configured for grupr (GRUPR_SNOWFLAKE_DB, GRUPR_SNOWFLAKE_SCHEMA).
+// A policy belongs to a single product dtap, and is specific for a data type and for the sets of consuming
+// product dtaps that should see masked or hashed values. The name of the policy ends with a hash of its body, so that
+// a policy with a given name never has to be altered: when consumers change, a new policy is attached.
+type MaskingPolicy struct {
+	ProductID string
+	DTAP      string
+	Kind      string // MASK; the body may hash values as well
+	DataType  string
+	Database  semantics.Ident
+	Schema    semantics.Ident
+	Name      semantics.Ident
+
+	body string // only known for policies computed from the YAML, not for policies queried from Snowflake
+}
+
+func newMaskingPolicy(semCnf *semantics.Config, cnf *Config, pdID semantics.ProductDTAPID, dataType string,
+	maskedFor map[semantics.ProductDTAPID]struct{}, hashedFor map[semantics.ProductDTAPID]struct{}) MaskingPolicy {
+	p := MaskingPolicy{
+		ProductID: pdID.ProductID,
+		DTAP:      pdID.DTAP,
+		Kind:      "MASK",
+		DataType:  dataType,
+		Database:  cnf.Database,
+		Schema:    cnf.Schema,
+	}
+	var b strings.Builder
+	b.WriteString("CASE\n  WHEN ")
+	b.WriteString(isProductDTAPInSession(semCnf, pdID))
+	b.WriteString(" THEN val\n")
+	for _, consumer := range slices.SortedFunc(maps.Keys(maskedFor), compareProductDTAPIDs) {
+		b.WriteString("  WHEN ")
+		b.WriteString(isProductDTAPInSession(semCnf, consumer))
+		b.WriteString(" THEN ")
+		b.WriteString(maskedValue(dataType))
+		b.WriteString("\n")
+	}
+	// Consumers that should see masked values in some interface and hashed values in another have already been
+	// handled by the previous branches: masking wins.
+	for _, consumer := range slices.SortedFunc(maps.Keys(hashedFor), compareProductDTAPIDs) {
+		b.WriteString("  WHEN ")
+		b.WriteString(isProductDTAPInSession(semCnf, consumer))
+		b.WriteString(" THEN ")
+		b.WriteString(hashedValue(cnf, pdID.ProductID, dataType))
+		b.WriteString("\n")
+	}
+	b.WriteString("  ELSE val\nEND")
+	p.body = b.String()
+	h := fnv.New32a()
+	h.Write([]byte(p.body))
+	p.Name = semCnf.Prefix + semantics.NewIdentUnquoted(p.ProductID) + semCnf.Infix + semantics.NewIdentUnquoted(p.DTAP) + semCnf.Infix +
+		semantics.NewIdentUnquoted(p.Kind) + semCnf.Infix + semantics.NewIdentUnquoted(dataType) + semCnf.Infix +
+		semantics.NewIdentUnquoted(fmt.Sprintf("%08x", h.Sum32()))
+	return p
+}
+
+func newMaskingPolicyFromIdent(semCnf *semantics.Config, cnf *Config, name semantics.Ident) (MaskingPolicy, error) {
+	p := MaskingPolicy{Database: cnf.Database, Schema: cnf.Schema, Name: name}
+	s := string(name)
+	if !strings.HasPrefix(s, string(semCnf.Prefix)) {
+		return p, fmt.Errorf("masking policy does not start with Grupr prefix: '%s'", p)
+	}
+	s = strings.TrimPrefix(s, string(semCnf.Prefix))
+	parts := strings.Split(s, string(semCnf.Infix))
+	if len(parts) != 5 {
+		return p, fmt.Errorf("masking policy does not have five parts: '%s'", p)
+	}
+	p.ProductID = strings.ToLower(parts[0])
+	p.DTAP = strings.ToLower(parts[1])
+	p.Kind = parts[2]
+	p.DataType = parts[3]
+	if p.Kind != "MASK" {
+		return p, fmt.Errorf("unimplemented kind '%s' for masking policy '%s'", p.Kind, p)
+	}
+	return p, nil
+}
+
+func isProductDTAPInSession(semCnf *semantics.Config, pdID semantics.ProductDTAPID) string {
+	// IS_ROLE_IN_SESSION takes the name of the role as a string, not as a (quoted) identifier
+	return fmt.Sprintf("IS_ROLE_IN_SESSION($$%s$$) OR IS_ROLE_IN_SESSION($$%s$$)",
+		string(newProductRole(semCnf, pdID.ProductID, pdID.DTAP, ModeRead).ID),
+		string(newProductRole(semCnf, pdID.ProductID, pdID.DTAP, ModeWrite).ID))
+}
+
+func compareProductDTAPIDs(a, b semantics.ProductDTAPID) int {
+	if c := strings.Compare(a.ProductID, b.ProductID); c != 0 {
+		return c
+	}
+	return strings.Compare(a.DTAP, b.DTAP)
+}
+
+func maskedValue(dataType string) string {
+	if dataType == "VARCHAR" {
+		return "'***MASKED***'"
+	}
+	return "NULL"
+}
+
+func canHash(dataType string) bool {
+	// The return type of a masking policy has to be the same as its input type
+	return dataType == "VARCHAR" || dataType == "NUMBER"
+}
+
+func hashedValue(cnf *Config, productID string, dataType string) string {
+	switch dataType {
+	case "VARCHAR":
+		return fmt.Sprintf("SHA2(CONCAT(%s, val))", hashSaltExpr(cnf, productID))
+	case "NUMBER":
+		return fmt.Sprintf("HASH(%s, val)", hashSaltExpr(cnf, productID))
+	default:
+		panic("data type can not be hashed")
+	}
+}
+
+func QueryMaskingPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) iter.Seq2[MaskingPolicy, error] {
+	return func(yield func(MaskingPolicy, error) bool) {
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW MASKING POLICIES IN SCHEMA IDENTIFIER($$%s.%s$$)
+	->> SELECT "name" FROM $1 WHERE "owner" = '%s'`, cnf.Database, cnf.Schema, string(cnf.Role)))
+		if err != nil {
+			yield(MaskingPolicy{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name semantics.Ident
+			if err = rows.Scan(&name); err != nil {
+				yield(MaskingPolicy{}, err)
+				return
+			}
+			if p, err := newMaskingPolicyFromIdent(semCnf, cnf, name); err != nil {
+				yield(MaskingPolicy{}, err)
+				return
+			} else if !yield(p, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(MaskingPolicy{}, err)
+		}
+	}
+}
+
+func (p MaskingPolicy) ProductDTAPID() semantics.ProductDTAPID {
+	return semantics.ProductDTAPID{ProductID: p.ProductID, DTAP: p.DTAP}
+}
+
+func (p MaskingPolicy) Create(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE MASKING POLICY IF NOT EXISTS %s AS (val %s) RETURNS %s ->
+%s
+COMMENT = 'Managed by Grupr'`, p, p.DataType, p.DataType, p.body))
+}
+
+func (p MaskingPolicy) QueryRefs(ctx context.Context, conn *sql.DB) iter.Seq2[PolicyRef, error] {
+	return QueryPolicyRefs(ctx, conn, ObjTpMaskingPolicy, p.Database, p.Schema, p.Name)
+}
+
+func (p MaskingPolicy) Drop(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Detach the policy from any columns it may still be attached to, e.g., because the product dtap
+	// that owned the policy is no longer in the YAML.
+	refs := []PolicyRef{}
+	for r, err := range p.QueryRefs(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		refs = append(refs, r)
+	}
+	if err := DoPolicyRefsSkipErrors(ctx, cnf, conn, slices.Values(refs), true); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`DROP MASKING POLICY IF EXISTS %s`, p)); err != nil {
+		log.Printf("masking policy %v could not be dropped: %v\n", p, err)
+	}
+	return nil
+}
+
+func (p MaskingPolicy) String() string {
+	return fmt.Sprintf("%v.%v.%v", p.Database, p.Schema, p.Name)
+}
@@ -0,0 +1,96 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestNewMaskingPolicy(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR"}
+	pdID := semantics.ProductDTAPID{ProductID: "crm", DTAP: "p"}
+	a := semantics.ProductDTAPID{ProductID: "a", DTAP: "p"}
+	b := semantics.ProductDTAPID{ProductID: "b", DTAP: "p"}
+	tests := []struct {
+		dataType  string
+		maskedFor map[semantics.ProductDTAPID]struct{}
+		want      string
+	}{
+		{
+			dataType: "VARCHAR",
+			want: `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN val
+  ELSE val
+END`,
+		},
+		{
+			dataType:  "VARCHAR",
+			maskedFor: map[semantics.ProductDTAPID]struct{}{b: {}, a: {}},
+			want: `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN val
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN '***MASKED***'
+  WHEN IS_ROLE_IN_SESSION($$_X_B_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_B_X_P_X_W$$) THEN '***MASKED***'
+  ELSE val
+END`,
+		},
+		{
+			dataType:  "DATE",
+			maskedFor: map[semantics.ProductDTAPID]struct{}{a: {}},
+			want: `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN val
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN NULL
+  ELSE val
+END`,
+		},
+	}
+	names := map[semantics.Ident]struct{}{}
+	for i, test := range tests {
+		p := newMaskingPolicy(semCnf, cnf, pdID, test.dataType, test.maskedFor, nil)
+		if p.body != test.want {
+			t.Errorf("test %d: body = %q, want %q", i, p.body, test.want)
+		}
+		if q := newMaskingPolicy(semCnf, cnf, pdID, test.dataType, test.maskedFor, nil); q.Name != p.Name {
+			t.Errorf("test %d: name is not deterministic: %v, %v", i, p.Name, q.Name)
+		}
+		if _, ok := names[p.Name]; ok {
+			t.Errorf("test %d: name %v is not unique", i, p.Name)
+		}
+		names[p.Name] = struct{}{}
+		// The name holds everything we need to know about a policy we find in Snowflake
+		q, err := newMaskingPolicyFromIdent(semCnf, cnf, p.Name)
+		if err != nil {
+			t.Errorf("test %d: newMaskingPolicyFromIdent(%v): %v", i, p.Name, err)
+		} else if q.ProductDTAPID() != pdID || q.Kind != p.Kind || q.DataType != test.dataType || q.String() != p.String() {
+			t.Errorf("test %d: newMaskingPolicyFromIdent(%v) = %v, want %v", i, p.Name, q, p)
+		}
+	}
+}
+
+func TestNewMaskingPolicyFromIdent(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR"}
+	tests := []struct {
+		name    semantics.Ident
+		wantErr bool
+	}{
+		{name: "_X_CRM_X_P_X_MASK_X_VARCHAR_X_0123abcd"},
+		{name: "CRM_X_P_X_MASK_X_VARCHAR_X_0123abcd", wantErr: true},
+		{name: "_X_CRM_X_P_X_MASK_X_VARCHAR", wantErr: true},
+		{name: "_X_CRM_X_P_X_HASH_X_VARCHAR_X_0123abcd", wantErr: true},
+	}
+	for _, test := range tests {
+		_, err := newMaskingPolicyFromIdent(semCnf, cnf, test.name)
+		if test.wantErr && err == nil {
+			t.Errorf("newMaskingPolicyFromIdent(%v): expected an error", test.name)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("newMaskingPolicyFromIdent(%v): %v", test.name, err)
+		}
+	}
+}
@@ -32,6 +32,7 @@ func (m Mode) getIdx() int {
 	case ModeWrite:
 		return 1
 	default:
+		// The operate role inherits its read privileges from the read role, so it does not need its own flags
 		panic("we don't currently use arrays with more modes")
 	}
 }
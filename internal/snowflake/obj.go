@@ -10,6 +10,12 @@ import (
 	"github.com/rwberendsen/grupr/internal/semantics"
 )
 
+type ObjectID struct {
+	Database semantics.Ident
+	Schema   semantics.Ident
+	Object   semantics.Ident
+}
+
 type Obj struct {
 	Name       semantics.Ident
 	ObjectType ObjType
@@ -20,12 +26,22 @@ func newObj(name semantics.Ident, objType ObjType, owner semantics.Ident) (Obj,
 	if len(name) == 0 {
 		return Obj{}, fmt.Errorf("zero length identifier")
 	}
-	if objType != ObjTpTable && objType != ObjTpView {
-		panic("ObjTp not implemented")
+	if !objType.isObjectLevel() {
+		return Obj{}, fmt.Errorf("object '%s': object type '%v' not implemented", name, objType)
 	}
 	return Obj{Name: name, ObjectType: objType, Owner: owner}, nil
 }
 
+func objTypesTableLikeList() string {
+	l := []string{}
+	for _, ot := range objTypesObjectLevel {
+		if ot.isTableLike() {
+			l = append(l, fmt.Sprintf("'%v'", ot))
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
 func QueryObjs(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) iter.Seq2[Obj, error] {
 	return func(yield func(Obj, error) bool) {
 		// When there are more than 10K results, paginate.
@@ -35,13 +51,18 @@ func QueryObjs(ctx context.Context, conn *sql.DB, db semantics.Ident, schema sem
 		var fromClause string
 		limit := 10000
 		for mayHaveMore {
+			// Dynamic and iceberg tables have kind TABLE, but they need their own grant syntax
 			rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW OBJECTS IN SCHEMA IDENTIFIER($$%s.%s$$) LIMIT %d%s ->>
 SELECT
     NULL AS n
   , "name" AS name
-  , "kind" As kind
+  , CASE
+    WHEN "is_dynamic" = 'Y' THEN 'DYNAMIC_TABLE'
+    WHEN "is_iceberg" = 'Y' THEN 'ICEBERG_TABLE'
+    ELSE REPLACE("kind", ' ', '_')
+    END AS kind
   , "owner" AS owner
-FROM $1 WHERE kind in ('%s', '%s')
+FROM $1 WHERE kind in (%s)
 UNION ALL
 SELECT
     COUNT(*)
@@ -49,7 +70,7 @@ SELECT
   , '' AS kind
   , '' AS owner
 FROM $1
-`, db, schema, limit, fromClause, ObjTpTable, ObjTpView))
+`, db, schema, limit, fromClause, objTypesTableLikeList()))
 			if err != nil {
 				if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
 					err = ErrObjectNotExistOrAuthorized
@@ -93,3 +114,125 @@ FROM $1
 		}
 	}
 }
+
+// hasUserFunctionsOrProcedures returns whether the schema holds any user functions or procedures; SHOW does not need
+// a running warehouse, unlike queries on the information schema
+func hasUserFunctionsOrProcedures(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) (bool, error) {
+	for _, ot := range [2]ObjType{ObjTpFunction, ObjTpProcedure} {
+		var n int
+		if err := conn.QueryRowContext(ctx, fmt.Sprintf(`SHOW USER %sS IN SCHEMA IDENTIFIER($$%s.%s$$) ->> SELECT COUNT(*) FROM $1`,
+			ot.sql(), db, schema)).Scan(&n); err != nil {
+			if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+				err = ErrObjectNotExistOrAuthorized
+			}
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func QueryFunctionsProcedures(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) iter.Seq2[Obj, error] {
+	// SHOW USER FUNCTIONS and SHOW USER PROCEDURES do not list owners, so we use the information schema instead; that
+	// needs a running warehouse, so we only query it for schemas that hold functions or procedures
+	return func(yield func(Obj, error) bool) {
+		if ok, err := hasUserFunctionsOrProcedures(ctx, conn, db, schema); err != nil {
+			yield(Obj{}, err)
+			return
+		} else if !ok {
+			return
+		}
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT function_name, '%v', argument_signature, function_owner
+FROM %s.INFORMATION_SCHEMA.FUNCTIONS WHERE function_schema = $$%s$$
+UNION ALL
+SELECT procedure_name, '%v', argument_signature, procedure_owner
+FROM %s.INFORMATION_SCHEMA.PROCEDURES WHERE procedure_schema = $$%s$$
+`, ObjTpFunction, db, string(schema), ObjTpProcedure, db, string(schema)))
+		if err != nil {
+			if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+				err = ErrObjectNotExistOrAuthorized
+			}
+			yield(Obj{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name semantics.Ident
+			var kind string
+			var argSignature *string
+			var owner *semantics.Ident // NULL if the owner is not a role
+			if err = rows.Scan(&name, &kind, &argSignature, &owner); err != nil {
+				err = fmt.Errorf("QueryFunctionsProcedures: error scanning row: %w", err)
+				yield(Obj{}, err)
+				return
+			}
+			objType := ParseObjType(kind)
+			if hasSignature(objType) {
+				if argSignature == nil {
+					yield(Obj{}, fmt.Errorf("%v %s.%s.%s has no argument signature", objType, db, schema, name))
+					return
+				}
+				if name, err = newSignatureIdent(name, *argSignature); err != nil {
+					yield(Obj{}, err)
+					return
+				}
+			}
+			var o semantics.Ident
+			if owner != nil {
+				o = *owner
+			}
+			if obj, err := newObj(name, objType, o); err != nil {
+				yield(Obj{}, err)
+				return
+			} else if !yield(obj, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("QueryFunctionsProcedures: error after looping over results: %w", err)
+			yield(Obj{}, err)
+		}
+	}
+}
+
+func QuerySequencesPipesTasksStreams(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) iter.Seq2[Obj, error] {
+	// SHOW OBJECTS does not list these, and the information schema has no views for tasks and streams
+	return func(yield func(Obj, error) bool) {
+		for _, ot := range []ObjType{ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream} {
+			rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW %sS IN SCHEMA IDENTIFIER($$%s.%s$$) ->> SELECT "name", "owner" FROM $1`,
+				ot.sql(), db, schema))
+			if err != nil {
+				if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+					err = ErrObjectNotExistOrAuthorized
+				}
+				yield(Obj{}, err)
+				return
+			}
+			for rows.Next() {
+				var name semantics.Ident
+				var owner semantics.Ident
+				if err = rows.Scan(&name, &owner); err != nil {
+					rows.Close()
+					yield(Obj{}, fmt.Errorf("QuerySequencesPipesTasksStreams: error scanning row: %w", err))
+					return
+				}
+				if obj, err := newObj(name, ot, owner); err != nil {
+					rows.Close()
+					yield(Obj{}, err)
+					return
+				} else if !yield(obj, nil) {
+					rows.Close()
+					return
+				}
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				yield(Obj{}, fmt.Errorf("QuerySequencesPipesTasksStreams: error after looping over results: %w", err))
+				return
+			}
+		}
+	}
+}
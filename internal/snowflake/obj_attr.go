@@ -7,4 +7,5 @@ import (
 type ObjAttr struct {
 	ObjectType ObjType
 	Owner      semantics.Ident
+	Columns    []Column // nil: not loaded
 }
@@ -16,6 +16,18 @@ type ObjCountsRow struct {
 	UserGroups  string
 	TableCount  int
 	ViewCount   int
+
+	MaterializedViewCount int
+	DynamicTableCount     int
+	ExternalTableCount    int
+	IcebergTableCount     int
+	EventTableCount       int
+	FunctionCount         int
+	ProcedureCount        int
+	SequenceCount         int
+	PipeCount             int
+	TaskCount             int
+	StreamCount           int
 }
 
 func StoreObjCountsRows(ctx context.Context, cnf *Config, conn *sql.DB, rows iter.Seq[ObjCountsRow]) error {
@@ -25,6 +37,17 @@ func StoreObjCountsRows(ctx context.Context, cnf *Config, conn *sql.DB, rows ite
 	var userGroups []string
 	var tableCounts []int
 	var viewCounts []int
+	var materializedViewCounts []int
+	var dynamicTableCounts []int
+	var externalTableCounts []int
+	var icebergTableCounts []int
+	var eventTableCounts []int
+	var functionCounts []int
+	var procedureCounts []int
+	var sequenceCounts []int
+	var pipeCounts []int
+	var taskCounts []int
+	var streamCounts []int
 
 	for r := range rows {
 		productIDs = append(productIDs, r.ProductID)
@@ -33,6 +56,17 @@ func StoreObjCountsRows(ctx context.Context, cnf *Config, conn *sql.DB, rows ite
 		userGroups = append(userGroups, r.UserGroups)
 		tableCounts = append(tableCounts, r.TableCount)
 		viewCounts = append(viewCounts, r.ViewCount)
+		materializedViewCounts = append(materializedViewCounts, r.MaterializedViewCount)
+		dynamicTableCounts = append(dynamicTableCounts, r.DynamicTableCount)
+		externalTableCounts = append(externalTableCounts, r.ExternalTableCount)
+		icebergTableCounts = append(icebergTableCounts, r.IcebergTableCount)
+		eventTableCounts = append(eventTableCounts, r.EventTableCount)
+		functionCounts = append(functionCounts, r.FunctionCount)
+		procedureCounts = append(procedureCounts, r.ProcedureCount)
+		sequenceCounts = append(sequenceCounts, r.SequenceCount)
+		pipeCounts = append(pipeCounts, r.PipeCount)
+		taskCounts = append(taskCounts, r.TaskCount)
+		streamCounts = append(streamCounts, r.StreamCount)
 	}
 
 	sql := fmt.Sprintf(`
@@ -42,7 +76,18 @@ CREATE OR REPLACE TABLE %v.%v.object_counts (
 	interface_id varchar,
 	user_groups varchar,
 	table_count integer,
-	view_count integer
+	view_count integer,
+	materialized_view_count integer,
+	dynamic_table_count integer,
+	external_table_count integer,
+	iceberg_table_count integer,
+	event_table_count integer,
+	function_count integer,
+	procedure_count integer,
+	sequence_count integer,
+	pipe_count integer,
+	task_count integer,
+	stream_count integer
 )
 `,
 		cnf.Database, cnf.Schema)
@@ -57,9 +102,20 @@ INSERT INTO %v.%v.object_counts (
 	interface_id,
 	user_groups,
 	table_count,
-	view_count
+	view_count,
+	materialized_view_count,
+	dynamic_table_count,
+	external_table_count,
+	iceberg_table_count,
+	event_table_count,
+	function_count,
+	procedure_count,
+	sequence_count,
+	pipe_count,
+	task_count,
+	stream_count
 )
-VALUES (?, ?, ?, ?, ?, ?)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `,
 		cnf.Database, cnf.Schema)
 	if err := runSQL(ctx, cnf, conn, sql,
@@ -68,7 +124,18 @@ VALUES (?, ?, ?, ?, ?, ?)
 		gosnowflake.Array(interfaceIDs),
 		gosnowflake.Array(userGroups),
 		gosnowflake.Array(tableCounts),
-		gosnowflake.Array(viewCounts)); err != nil {
+		gosnowflake.Array(viewCounts),
+		gosnowflake.Array(materializedViewCounts),
+		gosnowflake.Array(dynamicTableCounts),
+		gosnowflake.Array(externalTableCounts),
+		gosnowflake.Array(icebergTableCounts),
+		gosnowflake.Array(eventTableCounts),
+		gosnowflake.Array(functionCounts),
+		gosnowflake.Array(procedureCounts),
+		gosnowflake.Array(sequenceCounts),
+		gosnowflake.Array(pipeCounts),
+		gosnowflake.Array(taskCounts),
+		gosnowflake.Array(streamCounts)); err != nil {
 		return fmt.Errorf("insert stats: %v", err)
 	}
 	return nil
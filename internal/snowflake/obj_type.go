@@ -1,5 +1,9 @@
 package snowflake
 
+import (
+	"strings"
+)
+
 type ObjType int
 
 const (
@@ -7,50 +11,159 @@ const (
 	ObjTpAccount
 	ObjTpDatabase
 	ObjTpDatabaseRole
+	ObjTpDynamicTable
+	ObjTpEventTable
+	ObjTpExternalTable
+	ObjTpFunction
+	ObjTpIcebergTable
+	ObjTpMaskingPolicy
+	ObjTpMaterializedView
+	ObjTpPipe
+	ObjTpProcedure
 	ObjTpRole
+	ObjTpRowAccessPolicy
 	ObjTpSchema
+	ObjTpSequence
+	ObjTpStage
+	ObjTpStream
 	ObjTpTable
+	ObjTpTask
 	ObjTpUser
 	ObjTpView
 	ObjTpWarehouse
 )
 
+// Object types that live within a schema, and that are matched by object expressions; the position in this array
+// is used as an index in lookup tables, see getIdxObjectLevel.
+var objTypesObjectLevel = [...]ObjType{
+	ObjTpTable,
+	ObjTpView,
+	ObjTpMaterializedView,
+	ObjTpDynamicTable,
+	ObjTpExternalTable,
+	ObjTpIcebergTable,
+	ObjTpEventTable,
+	ObjTpFunction,
+	ObjTpProcedure,
+	ObjTpSequence,
+	ObjTpPipe,
+	ObjTpTask,
+	ObjTpStream,
+}
+
 func ParseObjType(s string) ObjType {
+	// In the output of SHOW GRANTS, we find MATERIALIZED_VIEW, but in CREATE privileges, we find MATERIALIZED VIEW
 	return map[string]ObjType{
-		"ACCOUNT":       ObjTpAccount,
-		"DATABASE":      ObjTpDatabase,
-		"DATABASE_ROLE": ObjTpDatabaseRole, // NB: in grant output we typically find DATABASE_ROLE (with underscore)
-		"ROLE":          ObjTpRole,
-		"SCHEMA":        ObjTpSchema,
-		"TABLE":         ObjTpTable,
-		"USER":          ObjTpUser,
-		"VIEW":          ObjTpView,
-		"WAREHOUSE":     ObjTpWarehouse,
-	}[s]
+		"ACCOUNT":           ObjTpAccount,
+		"DATABASE":          ObjTpDatabase,
+		"DATABASE_ROLE":     ObjTpDatabaseRole, // NB: in grant output we typically find DATABASE_ROLE (with underscore)
+		"DYNAMIC_TABLE":     ObjTpDynamicTable,
+		"EVENT_TABLE":       ObjTpEventTable,
+		"EXTERNAL_TABLE":    ObjTpExternalTable,
+		"FUNCTION":          ObjTpFunction,
+		"ICEBERG_TABLE":     ObjTpIcebergTable,
+		"MASKING_POLICY":    ObjTpMaskingPolicy,
+		"MATERIALIZED_VIEW": ObjTpMaterializedView,
+		"PIPE":              ObjTpPipe,
+		"PROCEDURE":         ObjTpProcedure,
+		"ROLE":              ObjTpRole,
+		"ROW_ACCESS_POLICY": ObjTpRowAccessPolicy,
+		"SCHEMA":            ObjTpSchema,
+		"SEQUENCE":          ObjTpSequence,
+		"STAGE":             ObjTpStage,
+		"STREAM":            ObjTpStream,
+		"TABLE":             ObjTpTable,
+		"TASK":              ObjTpTask,
+		"USER":              ObjTpUser,
+		"VIEW":              ObjTpView,
+		"WAREHOUSE":         ObjTpWarehouse,
+	}[strings.ReplaceAll(s, " ", "_")]
 }
 
 func (ot ObjType) String() string {
 	return map[ObjType]string{
-		ObjTpOther:        "OTHER",
-		ObjTpAccount:      "ACCOUNT",
-		ObjTpDatabase:     "DATABASE",
-		ObjTpDatabaseRole: "DATABASE_ROLE",
-		ObjTpRole:         "ROLE",
-		ObjTpSchema:       "SCHEMA",
-		ObjTpTable:        "TABLE",
-		ObjTpUser:         "USER",
-		ObjTpView:         "VIEW",
-		ObjTpWarehouse:    "WAREHOUSE",
+		ObjTpOther:            "OTHER",
+		ObjTpAccount:          "ACCOUNT",
+		ObjTpDatabase:         "DATABASE",
+		ObjTpDatabaseRole:     "DATABASE_ROLE",
+		ObjTpDynamicTable:     "DYNAMIC_TABLE",
+		ObjTpEventTable:       "EVENT_TABLE",
+		ObjTpExternalTable:    "EXTERNAL_TABLE",
+		ObjTpFunction:         "FUNCTION",
+		ObjTpIcebergTable:     "ICEBERG_TABLE",
+		ObjTpMaskingPolicy:    "MASKING_POLICY",
+		ObjTpMaterializedView: "MATERIALIZED_VIEW",
+		ObjTpPipe:             "PIPE",
+		ObjTpProcedure:        "PROCEDURE",
+		ObjTpRole:             "ROLE",
+		ObjTpRowAccessPolicy:  "ROW_ACCESS_POLICY",
+		ObjTpSchema:           "SCHEMA",
+		ObjTpSequence:         "SEQUENCE",
+		ObjTpStage:            "STAGE",
+		ObjTpStream:           "STREAM",
+		ObjTpTable:            "TABLE",
+		ObjTpTask:             "TASK",
+		ObjTpUser:             "USER",
+		ObjTpView:             "VIEW",
+		ObjTpWarehouse:        "WAREHOUSE",
 	}[ot]
 }
 
-func (ot ObjType) getIdxObjectLevel() int {
+// sql returns the object type as it is written in SQL statements, e.g., MATERIALIZED VIEW
+func (ot ObjType) sql() string {
+	return strings.ReplaceAll(ot.String(), "_", " ")
+}
+
+func (ot ObjType) isObjectLevel() bool {
+	for _, t := range objTypesObjectLevel {
+		if t == ot {
+			return true
+		}
+	}
+	return false
+}
+
+// isTableLike returns whether objects of this type are listed by SHOW OBJECTS, and have columns
+func (ot ObjType) isTableLike() bool {
+	switch ot {
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable:
+		return true
+	}
+	return false
+}
+
+// isSuspendable returns whether objects of this type need to be suspended while their ownership is transferred
+func (ot ObjType) isSuspendable() bool {
+	return ot == ObjTpPipe || ot == ObjTpTask
+}
+
+// getIdxObjectLevel returns the index of the object type in lookup tables; false if it is not an object living within
+// a schema, or not yet implemented
+func (ot ObjType) getIdxObjectLevel() (int, bool) {
+	for i, t := range objTypesObjectLevel {
+		if t == ot {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (ot ObjType) getPrivilegesObjectLevel() []Privilege {
 	switch ot {
-	case ObjTpTable:
-		return 0
-	case ObjTpView:
-		return 1
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpExternalTable, ObjTpIcebergTable:
+		return []Privilege{PrvSelect, PrvReferences}
+	case ObjTpDynamicTable, ObjTpEventTable:
+		// REFERENCES can not be granted on these
+		return []Privilege{PrvSelect}
+	case ObjTpFunction, ObjTpProcedure, ObjTpSequence:
+		return []Privilege{PrvUsage}
+	case ObjTpStream:
+		return []Privilege{PrvSelect}
+	case ObjTpPipe, ObjTpTask:
+		// Consumers read data, they have no business with the pipelines that load it
+		return []Privilege{}
 	default:
-		panic("not an object living within a schema or not yet implemented")
+		// Not an object living within a schema or not yet implemented; grupr does not grant anything on it
+		return []Privilege{}
 	}
 }
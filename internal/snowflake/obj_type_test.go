@@ -0,0 +1,122 @@
+package snowflake
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestObjTypeRoundTrip(t *testing.T) {
+	for _, ot := range objTypesObjectLevel {
+		s := ot.String()
+		if s == "" || s == ObjTpOther.String() {
+			t.Errorf("ObjType(%d).String() = %q", ot, s)
+			continue
+		}
+		if got := ParseObjType(s); got != ot {
+			t.Errorf("ParseObjType(%q) = %v, want %v", s, got, ot)
+		}
+		if got := ParseObjType(ot.sql()); got != ot {
+			t.Errorf("ParseObjType(%q) = %v, want %v", ot.sql(), got, ot)
+		}
+		if _, ok := ot.getIdxObjectLevel(); !ok {
+			t.Errorf("%v.getIdxObjectLevel(): not found", ot)
+		}
+	}
+}
+
+func TestObjTypeTableLike(t *testing.T) {
+	tests := []struct {
+		s          string // as found in SHOW GRANTS, or in CREATE privileges
+		want       ObjType
+		privileges []Privilege
+		sql        string
+	}{
+		{s: "TABLE", want: ObjTpTable, privileges: []Privilege{PrvSelect, PrvReferences}, sql: "TABLE"},
+		{s: "MATERIALIZED_VIEW", want: ObjTpMaterializedView, privileges: []Privilege{PrvSelect, PrvReferences}, sql: "MATERIALIZED VIEW"},
+		{s: "MATERIALIZED VIEW", want: ObjTpMaterializedView, privileges: []Privilege{PrvSelect, PrvReferences}, sql: "MATERIALIZED VIEW"},
+		{s: "DYNAMIC_TABLE", want: ObjTpDynamicTable, privileges: []Privilege{PrvSelect}, sql: "DYNAMIC TABLE"},
+		{s: "EXTERNAL_TABLE", want: ObjTpExternalTable, privileges: []Privilege{PrvSelect, PrvReferences}, sql: "EXTERNAL TABLE"},
+		{s: "ICEBERG_TABLE", want: ObjTpIcebergTable, privileges: []Privilege{PrvSelect, PrvReferences}, sql: "ICEBERG TABLE"},
+		{s: "EVENT_TABLE", want: ObjTpEventTable, privileges: []Privilege{PrvSelect}, sql: "EVENT TABLE"},
+	}
+	for _, test := range tests {
+		ot := ParseObjType(test.s)
+		if ot != test.want {
+			t.Errorf("ParseObjType(%q) = %v, want %v", test.s, ot, test.want)
+			continue
+		}
+		if !ot.isTableLike() || !ot.isObjectLevel() {
+			t.Errorf("%v: not table like, or not object level", ot)
+		}
+		if got := ot.getPrivilegesObjectLevel(); !slices.Equal(got, test.privileges) {
+			t.Errorf("%v.getPrivilegesObjectLevel() = %v, want %v", ot, got, test.privileges)
+		}
+		if got := ot.sql(); got != test.sql {
+			t.Errorf("%v.sql() = %q, want %q", ot, got, test.sql)
+		}
+	}
+	if got := ParseObjType("NETWORK_RULE"); got != ObjTpOther {
+		t.Errorf("ParseObjType(NETWORK_RULE) = %v, want %v", got, ObjTpOther)
+	}
+	if _, err := newObj("X", ObjTpOther, ""); err == nil {
+		t.Errorf("newObj with object type %v: expected an error", ObjTpOther)
+	}
+	if got := ObjTpOther.getPrivilegesObjectLevel(); len(got) != 0 {
+		t.Errorf("%v.getPrivilegesObjectLevel() = %v, want none", ObjTpOther, got)
+	}
+}
+
+func TestGrantTableLike(t *testing.T) {
+	tests := []struct {
+		privilege string
+		grantedOn string
+		name      string
+		want      string
+	}{
+		{
+			privilege: "SELECT",
+			grantedOn: "DYNAMIC_TABLE",
+			name:      "DB.SCHEMA.DT",
+			want:      `GRANT SELECT ON DYNAMIC TABLE IDENTIFIER($$"DB"."SCHEMA"."DT"$$) TO DATABASE ROLE IDENTIFIER($$"DB"."DB_ROLE"$$)`,
+		},
+		{
+			privilege: "REFERENCES",
+			grantedOn: "MATERIALIZED_VIEW",
+			name:      `DB.SCHEMA."my view"`,
+			want:      `GRANT REFERENCES ON MATERIALIZED VIEW IDENTIFIER($$"DB"."SCHEMA"."my view"$$) TO DATABASE ROLE IDENTIFIER($$"DB"."DB_ROLE"$$)`,
+		},
+		{
+			privilege: "SELECT",
+			grantedOn: "EVENT_TABLE",
+			name:      "DB.SCHEMA.EVENTS",
+			want:      `GRANT SELECT ON EVENT TABLE IDENTIFIER($$"DB"."SCHEMA"."EVENTS"$$) TO DATABASE ROLE IDENTIFIER($$"DB"."DB_ROLE"$$)`,
+		},
+	}
+	for _, test := range tests {
+		g, err := newGrantToRole(test.privilege, "", test.grantedOn, test.name, nil, ObjTpDatabaseRole, "DB", "DB_ROLE", false, "")
+		if err != nil {
+			t.Errorf("newGrantToRole(%s, %s, %s): %v", test.privilege, test.grantedOn, test.name, err)
+			continue
+		}
+		if got := g.buildSQLGrant(false); got != test.want {
+			t.Errorf("buildSQLGrant() = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestFutureGrantTableLike(t *testing.T) {
+	g := FutureGrant{
+		Privileges:        []PrivilegeComplete{PrivilegeComplete{Privilege: PrvSelect}},
+		GrantedOn:         ObjTpMaterializedView,
+		GrantedIn:         ObjTpSchema,
+		Database:          "DB",
+		Schema:            "SCHEMA",
+		GrantedTo:         ObjTpDatabaseRole,
+		GrantedToDatabase: "DB",
+		GrantedToName:     "DB_ROLE",
+	}
+	want := `GRANT SELECT ON FUTURE MATERIALIZED VIEWS IN SCHEMA IDENTIFIER($$"DB"."SCHEMA"$$) TO DATABASE ROLE IDENTIFIER($$"DB"."DB_ROLE"$$)`
+	if got := g.buildSQLGrant(false); got != want {
+		t.Errorf("buildSQLGrant() = %q, want %q", got, want)
+	}
+}
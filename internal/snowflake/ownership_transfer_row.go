@@ -0,0 +1,196 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// An OwnershipTransferRow reports an object that grupr transfers ownership of: either it claims the object for the
+// product write role, or it gives it away, because it no longer belongs to the product dtap. OwnerGrantedWriteRole
+// says, when claiming, whether the current owner has been granted the write role, so that it keeps ownership
+// indirectly; and, when giving away, whether the new owner had been granted the write role. CopiedGrants lists the
+// outbound grants on the object, which are copied to the new owner; it is only filled in dry run mode.
+type OwnershipTransferRow struct {
+	ProductID             string
+	DTAP                  string
+	ObjectType            string
+	Database              string
+	Schema                string
+	Object                string
+	CurrentOwner          string
+	NewOwner              string
+	OwnerGrantedWriteRole bool
+	CopiedGrants          string
+}
+
+func (r OwnershipTransferRow) String() string {
+	return fmt.Sprintf("product '%s', dtap '%s': %s %s.%s.%s from '%s' to '%s' (owner granted write role: %t; copied grants: %s)",
+		r.ProductID, r.DTAP, r.ObjectType, r.Database, r.Schema, r.Object, r.CurrentOwner, r.NewOwner, r.OwnerGrantedWriteRole,
+		r.CopiedGrants)
+}
+
+// queryOutboundGrants returns the privileges on an object, other than OWNERSHIP, as a comma separated list
+func queryOutboundGrants(ctx context.Context, conn *sql.DB, ot ObjType, db semantics.Ident, schema semantics.Ident, obj semantics.Ident) (string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW GRANTS ON %s %s ->>
+SELECT "privilege", "granted_to", "grantee_name" FROM $1 WHERE "privilege" != 'OWNERSHIP'`, ot.sql(), objSQL(ot, db, schema, obj)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	l := []string{}
+	for rows.Next() {
+		var privilege, grantedTo, granteeName string
+		if err = rows.Scan(&privilege, &grantedTo, &granteeName); err != nil {
+			return "", fmt.Errorf("queryOutboundGrants: error scanning row: %w", err)
+		}
+		l = append(l, fmt.Sprintf("%s TO %s %s", privilege, grantedTo, granteeName))
+	}
+	return strings.Join(l, ", "), rows.Err()
+}
+
+// An ownershipTransferKey identifies an ownership grant; grants can be retried, when objects were dropped
+// concurrently, and we report each transfer once
+type ownershipTransferKey struct {
+	ObjectType ObjType
+	ObjectID
+	NewOwner semantics.Ident
+}
+
+// addOwnershipTransfer adds an ownership grant that we did to the report; currentOwner is who owned the object
+// before, and ownerGrantedWriteRole whether the current or new owner has been granted the write role. We only query
+// the outbound grants on the object in dry run mode, to review them before merging a change in YAML; querying them
+// one object at a time would slow down real runs.
+func (pd *ProductDTAP) addOwnershipTransfer(ctx context.Context, cnf *Config, conn *sql.DB, g Grant,
+	currentOwner semantics.Ident, ownerGrantedWriteRole bool) {
+	k := ownershipTransferKey{ObjectType: g.GrantedOn, ObjectID: ObjectID{Database: g.Database, Schema: g.Schema, Object: g.Object},
+		NewOwner: g.GrantedToName}
+	if _, ok := pd.ownershipTransfers[k]; ok {
+		return
+	}
+	r := OwnershipTransferRow{
+		ProductID:             pd.ProductID,
+		DTAP:                  pd.DTAP,
+		ObjectType:            g.GrantedOn.String(),
+		Database:              string(g.Database),
+		Schema:                string(g.Schema),
+		Object:                string(g.Object),
+		CurrentOwner:          string(currentOwner),
+		NewOwner:              string(g.GrantedToName),
+		OwnerGrantedWriteRole: ownerGrantedWriteRole,
+	}
+	if cnf.DryRun {
+		var err error
+		if r.CopiedGrants, err = queryOutboundGrants(ctx, conn, g.GrantedOn, g.Database, g.Schema, g.Object); err != nil {
+			// The report should not stop us from transferring ownership
+			log.Printf("WARN: could not query grants on %s %s.%s.%s: %v", r.ObjectType, r.Database, r.Schema, r.Object, err)
+			r.CopiedGrants = "unknown"
+		}
+	}
+	if pd.ownershipTransfers == nil {
+		pd.ownershipTransfers = map[ownershipTransferKey]OwnershipTransferRow{}
+	}
+	pd.ownershipTransfers[k] = r
+}
+
+func (g *Grupin) GetOwnershipTransferRows() iter.Seq[OwnershipTransferRow] {
+	return func(yield func(OwnershipTransferRow) bool) {
+		for _, pd := range g.ProductDTAPs {
+			for _, r := range pd.ownershipTransfers {
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// StoreOwnershipTransferRows logs the report, and appends it to the ownership_transfers table in the schema configured
+// for grupr; in dry run mode, only the former happens, so the report can be reviewed before merging a change in YAML
+func StoreOwnershipTransferRows(ctx context.Context, cnf *Config, conn *sql.DB, rows iter.Seq[OwnershipTransferRow]) error {
+	var productIDs []string
+	var dtaps []string
+	var objectTypes []string
+	var databases []string
+	var schemas []string
+	var objects []string
+	var currentOwners []string
+	var newOwners []string
+	var ownerGrantedWriteRoles []bool
+	var copiedGrants []string
+
+	for r := range rows {
+		log.Printf("Ownership transfer: %v", r)
+		productIDs = append(productIDs, r.ProductID)
+		dtaps = append(dtaps, r.DTAP)
+		objectTypes = append(objectTypes, r.ObjectType)
+		databases = append(databases, r.Database)
+		schemas = append(schemas, r.Schema)
+		objects = append(objects, r.Object)
+		currentOwners = append(currentOwners, r.CurrentOwner)
+		newOwners = append(newOwners, r.NewOwner)
+		ownerGrantedWriteRoles = append(ownerGrantedWriteRoles, r.OwnerGrantedWriteRole)
+		copiedGrants = append(copiedGrants, r.CopiedGrants)
+	}
+
+	sql := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v.%v.ownership_transfers (
+	product_id varchar,
+	dtap varchar,
+	object_type varchar,
+	database_name varchar,
+	schema_name varchar,
+	object_name varchar,
+	current_owner varchar,
+	new_owner varchar,
+	owner_granted_write_role boolean,
+	copied_grants varchar,
+	transferred_at timestamp_ltz
+)
+`,
+		cnf.Database, cnf.Schema)
+	if err := runSQL(ctx, cnf, conn, sql); err != nil {
+		return fmt.Errorf("create table: %v", err)
+	}
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	sql = fmt.Sprintf(`
+INSERT INTO %v.%v.ownership_transfers (
+	product_id,
+	dtap,
+	object_type,
+	database_name,
+	schema_name,
+	object_name,
+	current_owner,
+	new_owner,
+	owner_granted_write_role,
+	copied_grants,
+	transferred_at
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP())
+`,
+		cnf.Database, cnf.Schema)
+	if err := runSQL(ctx, cnf, conn, sql,
+		gosnowflake.Array(productIDs),
+		gosnowflake.Array(dtaps),
+		gosnowflake.Array(objectTypes),
+		gosnowflake.Array(databases),
+		gosnowflake.Array(schemas),
+		gosnowflake.Array(objects),
+		gosnowflake.Array(currentOwners),
+		gosnowflake.Array(newOwners),
+		gosnowflake.Array(ownerGrantedWriteRoles),
+		gosnowflake.Array(copiedGrants)); err != nil {
+		return fmt.Errorf("insert ownership transfers: %v", err)
+	}
+	return nil
+}
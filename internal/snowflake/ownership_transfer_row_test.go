@@ -0,0 +1,70 @@
+package snowflake
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestAddOwnershipTransfer(t *testing.T) {
+	pdID := semantics.ProductDTAPID{ProductID: "crm", DTAP: "p"}
+	pd := &ProductDTAP{ProductDTAPID: pdID}
+	claim := Grant{
+		Privileges:    []PrivilegeComplete{PrivilegeComplete{Privilege: PrvOwnership}},
+		GrantedOn:     ObjTpTable,
+		Database:      "DB",
+		Schema:        "my schema",
+		Object:        `my "table"`,
+		GrantedTo:     ObjTpRole,
+		GrantedToName: "_X_CRM_X_P_X_W",
+	}
+	giveAway := claim
+	giveAway.Object = "OTHER"
+	giveAway.GrantedToName = "SYSADMIN"
+
+	cnf := &Config{} // not a dry run, so we do not query outbound grants
+	ctx := context.Background()
+	pd.addOwnershipTransfer(ctx, cnf, nil, claim, "LEGACY_OWNER", true)
+	pd.addOwnershipTransfer(ctx, cnf, nil, claim, "LEGACY_OWNER", true) // retried, reported once
+	pd.addOwnershipTransfer(ctx, cnf, nil, giveAway, "_X_CRM_X_P_X_W", false)
+
+	g := &Grupin{ProductDTAPs: map[semantics.ProductDTAPID]*ProductDTAP{pdID: pd}}
+	got := slices.SortedFunc(g.GetOwnershipTransferRows(), func(a, b OwnershipTransferRow) int {
+		return strings.Compare(a.Object, b.Object)
+	})
+	want := []OwnershipTransferRow{
+		{
+			ProductID:             "crm",
+			DTAP:                  "p",
+			ObjectType:            "TABLE",
+			Database:              "DB",
+			Schema:                "my schema",
+			Object:                "OTHER",
+			CurrentOwner:          "_X_CRM_X_P_X_W",
+			NewOwner:              "SYSADMIN",
+			OwnerGrantedWriteRole: false,
+		},
+		{
+			ProductID:             "crm",
+			DTAP:                  "p",
+			ObjectType:            "TABLE",
+			Database:              "DB",
+			Schema:                "my schema",
+			Object:                `my "table"`, // names are stored as they are, not quoted
+			CurrentOwner:          "LEGACY_OWNER",
+			NewOwner:              "_X_CRM_X_P_X_W",
+			OwnerGrantedWriteRole: true,
+		},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetOwnershipTransferRows() = %v, want %v", got, want)
+	}
+
+	wantString := `product 'crm', dtap 'p': TABLE DB.my schema.OTHER from '_X_CRM_X_P_X_W' to 'SYSADMIN' (owner granted write role: false; copied grants: )`
+	if s := want[0].String(); s != wantString {
+		t.Errorf("String() = %q, want %q", s, wantString)
+	}
+}
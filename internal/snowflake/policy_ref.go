@@ -0,0 +1,154 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// A PolicyRef is a policy attached to a column of a table or view; for row access policies, Column is the column
+// that is passed to the policy.
+type PolicyRef struct {
+	PolicyKind     ObjType
+	PolicyDatabase semantics.Ident
+	PolicySchema   semantics.Ident
+	Policy         semantics.Ident
+	ObjectType     ObjType
+	Database       semantics.Ident
+	Schema         semantics.Ident
+	Object         semantics.Ident
+	Column         semantics.Ident
+
+	// Only used for row access policies, where only one policy can be attached to an object, and where we need
+	// to drop the policy that is attached currently before adding another one
+	ReplacesPolicy semantics.Ident
+}
+
+func (r PolicyRef) ColumnID() ColumnID {
+	return ColumnID{Database: r.Database, Schema: r.Schema, Object: r.Object, Column: r.Column}
+}
+
+func (r PolicyRef) ObjectID() ObjectID {
+	return ObjectID{Database: r.Database, Schema: r.Schema, Object: r.Object}
+}
+
+func (r PolicyRef) buildSQL(unset bool) string {
+	switch r.PolicyKind {
+	case ObjTpMaskingPolicy:
+		if unset {
+			return fmt.Sprintf(`ALTER %s IDENTIFIER($$%s.%s.%s$$) MODIFY COLUMN %s UNSET MASKING POLICY`,
+				r.ObjectType.sql(), r.Database, r.Schema, r.Object, r.Column.Quote())
+		}
+		// FORCE replaces any masking policy that is currently attached to the column
+		return fmt.Sprintf(`ALTER %s IDENTIFIER($$%s.%s.%s$$) MODIFY COLUMN %s SET MASKING POLICY %s.%s.%s FORCE`,
+			r.ObjectType.sql(), r.Database, r.Schema, r.Object, r.Column.Quote(), r.PolicyDatabase, r.PolicySchema, r.Policy)
+	case ObjTpRowAccessPolicy:
+		if unset {
+			return fmt.Sprintf(`ALTER %s IDENTIFIER($$%s.%s.%s$$) DROP ROW ACCESS POLICY %s.%s.%s`,
+				r.ObjectType.sql(), r.Database, r.Schema, r.Object, r.PolicyDatabase, r.PolicySchema, r.Policy)
+		}
+		if r.ReplacesPolicy != "" {
+			return fmt.Sprintf(`ALTER %s IDENTIFIER($$%s.%s.%s$$) DROP ROW ACCESS POLICY %s.%s.%s, ADD ROW ACCESS POLICY %s.%s.%s ON (%s)`,
+				r.ObjectType.sql(), r.Database, r.Schema, r.Object, r.PolicyDatabase, r.PolicySchema, r.ReplacesPolicy,
+				r.PolicyDatabase, r.PolicySchema, r.Policy, r.Column.Quote())
+		}
+		return fmt.Sprintf(`ALTER %s IDENTIFIER($$%s.%s.%s$$) ADD ROW ACCESS POLICY %s.%s.%s ON (%s)`,
+			r.ObjectType.sql(), r.Database, r.Schema, r.Object, r.PolicyDatabase, r.PolicySchema, r.Policy, r.Column.Quote())
+	default:
+		panic("policy kind not implemented")
+	}
+}
+
+func QueryPolicyRefs(ctx context.Context, conn *sql.DB, kind ObjType, db semantics.Ident, schema semantics.Ident,
+	policy semantics.Ident) iter.Seq2[PolicyRef, error] {
+	return func(yield func(PolicyRef, error) bool) {
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT
+    ref_database_name
+  , ref_schema_name
+  , ref_entity_name
+  , UPPER(ref_entity_domain)
+  , COALESCE(ref_column_name, PARSE_JSON(ref_arg_column_names)[0]::VARCHAR) -- row access policies have argument columns
+FROM TABLE(%s.INFORMATION_SCHEMA.POLICY_REFERENCES(POLICY_NAME => $$%s.%s.%s$$))
+`, db, db, schema, policy))
+		if err != nil {
+			if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+				err = ErrObjectNotExistOrAuthorized
+			}
+			yield(PolicyRef{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			r := PolicyRef{PolicyKind: kind, PolicyDatabase: db, PolicySchema: schema, Policy: policy}
+			var domain string
+			if err = rows.Scan(&r.Database, &r.Schema, &r.Object, &domain, &r.Column); err != nil {
+				err = fmt.Errorf("QueryPolicyRefs: error scanning row: %w", err)
+				yield(PolicyRef{}, err)
+				return
+			}
+			r.ObjectType = ParseObjType(domain)
+			if !yield(r, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("QueryPolicyRefs: error after looping over results: %w", err)
+			yield(PolicyRef{}, err)
+		}
+	}
+}
+
+func DoPolicyRefs(ctx context.Context, cnf *Config, conn *sql.DB, refs iter.Seq[PolicyRef], unset bool) error {
+	// Runs statements in batches
+	buf := make([]string, cnf.StmtBatchSize)
+	i := 0
+	for r := range refs {
+		if i == cnf.StmtBatchSize {
+			if err := runMultipleSQL(ctx, cnf, conn, strings.Join(buf, ";"), i); err != nil {
+				return err
+			}
+			i = 0
+		}
+		buf[i] = r.buildSQL(unset)
+		i++
+	}
+	if i > 0 {
+		if err := runMultipleSQL(ctx, cnf, conn, strings.Join(buf[0:i], ";"), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func DoPolicyRefsIndividually(ctx context.Context, cnf *Config, conn *sql.DB, refs iter.Seq[PolicyRef], unset bool) error {
+	// Used when an object may have a policy attached that is not managed by grupr, which would make the statement fail;
+	// we do not want to stop managing the rest of the product dtap because of that.
+	for r := range refs {
+		err := runSQL(ctx, cnf, conn, r.buildSQL(unset))
+		if err == nil || err == ErrObjectNotExistOrAuthorized {
+			// The object may have been dropped since we queried it; nothing to do
+			continue
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		// We can not tell a policy that is not managed by grupr from other failures by the error, so we deliberately
+		// log all of them and carry on with the next object
+		log.Printf("WARN: %s: %v\n", r.buildSQL(unset), err)
+	}
+	return nil
+}
+
+func DoPolicyRefsSkipErrors(ctx context.Context, cnf *Config, conn *sql.DB, refs iter.Seq[PolicyRef], unset bool) error {
+	for r := range refs {
+		if err := runSQL(ctx, cnf, conn, r.buildSQL(unset)); err != nil && err != ErrObjectNotExistOrAuthorized {
+			return err
+		}
+	}
+	return nil
+}
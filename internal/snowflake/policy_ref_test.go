@@ -0,0 +1,71 @@
+package snowflake
+
+import (
+	"testing"
+)
+
+func TestPolicyRefBuildSQL(t *testing.T) {
+	masked := PolicyRef{
+		PolicyKind:     ObjTpMaskingPolicy,
+		PolicyDatabase: "DB",
+		PolicySchema:   "GRUPR",
+		Policy:         "MP_1",
+		ObjectType:     ObjTpTable,
+		Database:       "DB",
+		Schema:         "SCHEMA",
+		Object:         "CUSTOMER",
+		Column:         "SSN",
+	}
+	quoted := masked
+	quoted.Column = `my "ssn"`
+	rowAccess := PolicyRef{
+		PolicyKind:     ObjTpRowAccessPolicy,
+		PolicyDatabase: "DB",
+		PolicySchema:   "GRUPR",
+		Policy:         "RAP_1",
+		ObjectType:     ObjTpView,
+		Database:       "DB",
+		Schema:         "SCHEMA",
+		Object:         "CUSTOMER",
+		Column:         "country",
+	}
+	replacing := rowAccess
+	replacing.ReplacesPolicy = "RAP_0"
+	tests := []struct {
+		ref   PolicyRef
+		unset bool
+		want  string
+	}{
+		{
+			ref:  masked,
+			want: `ALTER TABLE IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) MODIFY COLUMN "SSN" SET MASKING POLICY "DB"."GRUPR"."MP_1" FORCE`,
+		},
+		{
+			ref:   masked,
+			unset: true,
+			want:  `ALTER TABLE IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) MODIFY COLUMN "SSN" UNSET MASKING POLICY`,
+		},
+		{
+			ref:  quoted,
+			want: `ALTER TABLE IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) MODIFY COLUMN "my ""ssn""" SET MASKING POLICY "DB"."GRUPR"."MP_1" FORCE`,
+		},
+		{
+			ref:  rowAccess,
+			want: `ALTER VIEW IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) ADD ROW ACCESS POLICY "DB"."GRUPR"."RAP_1" ON ("country")`,
+		},
+		{
+			ref:  replacing,
+			want: `ALTER VIEW IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) DROP ROW ACCESS POLICY "DB"."GRUPR"."RAP_0", ADD ROW ACCESS POLICY "DB"."GRUPR"."RAP_1" ON ("country")`,
+		},
+		{
+			ref:   rowAccess,
+			unset: true,
+			want:  `ALTER VIEW IDENTIFIER($$"DB"."SCHEMA"."CUSTOMER"$$) DROP ROW ACCESS POLICY "DB"."GRUPR"."RAP_1"`,
+		},
+	}
+	for i, test := range tests {
+		if got := test.ref.buildSQL(test.unset); got != test.want {
+			t.Errorf("test %d: buildSQL(%v) = %q, want %q", i, test.unset, got, test.want)
+		}
+	}
+}
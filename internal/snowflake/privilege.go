@@ -8,9 +8,11 @@ const (
 	PrvMonitor
 	PrvOperate
 	PrvOwnership
+	PrvRead
 	PrvReferences
 	PrvSelect
 	PrvUsage
+	PrvWrite
 )
 
 func ParsePrivilege(p string) Privilege {
@@ -19,9 +21,11 @@ func ParsePrivilege(p string) Privilege {
 		"MONITOR":    PrvMonitor,
 		"OPERATE":    PrvOperate,
 		"OWNERSHIP":  PrvOwnership,
+		"READ":       PrvRead,
 		"REFERENCES": PrvReferences,
 		"SELECT":     PrvSelect,
 		"USAGE":      PrvUsage,
+		"WRITE":      PrvWrite,
 	}[p]
 }
 
@@ -32,9 +36,11 @@ func (p Privilege) String() string {
 		PrvMonitor:    "MONITOR",
 		PrvOperate:    "OPERATE",
 		PrvOwnership:  "OWNERSHIP",
+		PrvRead:       "READ",
 		PrvReferences: "REFERENCES",
 		PrvSelect:     "SELECT",
 		PrvUsage:      "USAGE",
+		PrvWrite:      "WRITE",
 	}[p]
 }
 
@@ -44,27 +50,33 @@ func (p Privilege) getIdxObjectLevel() int {
 		return 0
 	case PrvReferences:
 		return 1
+	case PrvUsage:
+		return 2
 	default:
 		panic("not an object level privilege or not yet implemented")
 	}
 }
 
-func setFlagPrivilegeWarehouse(flags [2]bool, setFlag Privilege) [2]bool {
+func setFlagPrivilegeWarehouse(flags [3]bool, setFlag Privilege) [3]bool {
 	switch setFlag {
 	case PrvUsage:
 		flags[0] = true
 	case PrvOperate:
 		flags[1] = true
+	case PrvMonitor:
+		flags[2] = true
 	}
 	return flags
 }
 
-func hasFlagPrivilegeWarehouse(flags [2]bool, flag Privilege) bool {
+func hasFlagPrivilegeWarehouse(flags [3]bool, flag Privilege) bool {
 	switch flag {
 	case PrvUsage:
 		return flags[0]
 	case PrvOperate:
 		return flags[1]
+	case PrvMonitor:
+		return flags[2]
 	}
 	return false
 }
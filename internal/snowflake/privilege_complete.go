@@ -18,7 +18,7 @@ func ParsePrivilegeComplete(p string, cot string) PrivilegeComplete {
 
 func (p PrivilegeComplete) String() string {
 	if p.Privilege == PrvCreate && p.CreateObjectType != ObjTpOther {
-		return fmt.Sprintf("%s %s", p.Privilege, p.CreateObjectType)
+		return fmt.Sprintf("%s %s", p.Privilege, p.CreateObjectType.sql())
 	}
 	return fmt.Sprintf("%s", p.Privilege)
 }
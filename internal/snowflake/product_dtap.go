@@ -8,6 +8,7 @@ import (
 	"log"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
 	"github.com/rwberendsen/grupr/internal/syntax"
@@ -18,20 +19,32 @@ type ProductDTAP struct {
 	IsProd            bool
 	IsManual          bool
 	BlockCentralTeams bool
+	ConfiscateAfter   *time.Duration // from the product YAML, nil if not set there
 	*Interface
-	Interfaces            map[string]*Interface
-	Consumes              map[syntax.InterfaceID]string // value is source dtap
-	ReadRole              ProductRole
-	WriteRole             ProductRole
-	GrantReadRoleToUsers  map[semantics.Ident]bool    // initially set to false, then to true if GRANTS are found in Snowflake
-	GrantWriteRoleToUsers map[semantics.Ident]bool    // initially set to false, then to true if GRANTS are found in Snowflake
-	ReadWarehouses        map[semantics.Ident][2]bool // initially set to false, then to true if GRANTS (USAGE, OPERATE) are found in Snowflake
-	WriteWarehouses       map[semantics.Ident][2]bool // initially set to false, then to true if GRANTS (USAGE, OPERATE) are found in Snowflake
+	Interfaces              map[string]*Interface
+	Consumes                map[syntax.InterfaceID]string // value is source dtap
+	ReadRole                ProductRole
+	WriteRole               ProductRole
+	OperateRole             ProductRole
+	GrantReadRoleToUsers    map[semantics.Ident]bool        // initially set to false, then to true if GRANTS are found in Snowflake
+	GrantWriteRoleToUsers   map[semantics.Ident]bool        // initially set to false, then to true if GRANTS are found in Snowflake
+	GrantOperateRoleToUsers map[semantics.Ident]bool        // initially set to false, then to true if GRANTS are found in Snowflake
+	ReadWarehouses          map[semantics.Ident][3]bool     // initially set to false, then to true if GRANTS (USAGE, OPERATE) are found in Snowflake
+	WriteWarehouses         map[semantics.Ident][3]bool     // initially set to false, then to true if GRANTS (USAGE, OPERATE) are found in Snowflake
+	OperateWarehouses       map[semantics.Ident][3]bool     // initially set to false, then to true if GRANTS (OPERATE, MONITOR) are found in Snowflake
+	ReadStages              map[ObjectID]map[Privilege]bool // initially set to false, then to true if GRANTS are found in Snowflake
+	WriteStages             map[ObjectID]map[Privilege]bool // initially set to false, then to true if GRANTS are found in Snowflake
+	UserGroupColumn         semantics.ColExprs
 
 	writeRoleGrantedToUserManagedRoles map[semantics.Ident]struct{}
+	legacyOwnerGrants                  map[semantics.Ident]time.Time // when grupr granted the write role to user managed roles
+	isReadRoleGrantedToOperateRole     bool
+	breakGlass                         []BreakGlass // in force
+	expiredBreakGlass                  []BreakGlass
 	userManagedOwnersOfObjects         map[semantics.Ident]struct{}
 	refreshCount                       int // how many times has this ProductDTAP been refreshed: populated with Snowflake objects
 	matchedAccountObjects              map[semantics.ObjExpr]*matchedAccountObjs
+	loadColumns                        map[semantics.ObjExpr]bool // whether we need columns of objects, to attach policies
 
 	// These are only appended to, as we query different kinds of privileges granted to our product roles
 	toRevoke []Grant
@@ -41,29 +54,64 @@ type ProductDTAP struct {
 	toRevokeFutureObjects []FutureGrant
 	toTransferOwnership   []Grant
 
+	// Ownership grants done (or printed, in dry run mode) during this run, see ownership_transfer_row.go
+	ownershipTransfers map[ownershipTransferKey]OwnershipTransferRow
+
+	// Masking policies; the existing ones are set once, the others are recomputed every time we grant privileges on objects
+	existingMaskingPolicies map[semantics.Ident]MaskingPolicy
+	maskingPolicies         map[semantics.Ident]MaskingPolicy
+	maskedColumns           map[ColumnID]PolicyRef
+
+	// Row access policies, idem
+	existingRowAccessPolicies map[semantics.Ident]RowAccessPolicy
+	rowAccessPolicies         map[semantics.Ident]RowAccessPolicy
+	rowAccessObjects          map[ObjectID]PolicyRef
+
 	// Used for product dtap roles that exist in Snowflake but not in the YAML
 	isZombie bool
 }
 
 func NewProductDTAP(pdID semantics.ProductDTAPID, isProd bool, pSem semantics.Product, userGroupMappings map[string]semantics.UserGroupMapping,
-	svcs map[string]semantics.ServiceAccount, teams map[string]semantics.Team) *ProductDTAP {
+	svcs map[string]semantics.ServiceAccount, teams map[string]semantics.Team, classes map[string]syntax.Class, now time.Time) *ProductDTAP {
 	pd := &ProductDTAP{
-		ProductDTAPID:         pdID,
-		IsProd:                isProd,
-		IsManual:              pSem.DTAPs.IsManual(pdID.DTAP),
-		BlockCentralTeams:     pSem.BlockCentralTeams,
-		Interface:             NewInterface(pdID.DTAP, pSem.InterfaceMetadata, userGroupMappings[pSem.UserGroupMappingID]),
-		Interfaces:            map[string]*Interface{},
-		Consumes:              map[syntax.InterfaceID]string{},
-		GrantReadRoleToUsers:  map[semantics.Ident]bool{},
-		GrantWriteRoleToUsers: map[semantics.Ident]bool{},
-		ReadWarehouses:        map[semantics.Ident][2]bool{},
-		WriteWarehouses:       map[semantics.Ident][2]bool{},
-		matchedAccountObjects: map[semantics.ObjExpr]*matchedAccountObjs{},
+		ProductDTAPID:             pdID,
+		IsProd:                    isProd,
+		IsManual:                  pSem.DTAPs.IsManual(pdID.DTAP),
+		BlockCentralTeams:         pSem.BlockCentralTeams,
+		ConfiscateAfter:           pSem.ConfiscateAfter,
+		Interface:                 NewInterface(pdID.DTAP, pSem.InterfaceMetadata, userGroupMappings[pSem.UserGroupMappingID], classes),
+		Interfaces:                map[string]*Interface{},
+		Consumes:                  map[syntax.InterfaceID]string{},
+		GrantReadRoleToUsers:      map[semantics.Ident]bool{},
+		GrantWriteRoleToUsers:     map[semantics.Ident]bool{},
+		GrantOperateRoleToUsers:   map[semantics.Ident]bool{},
+		ReadWarehouses:            map[semantics.Ident][3]bool{},
+		WriteWarehouses:           map[semantics.Ident][3]bool{},
+		OperateWarehouses:         map[semantics.Ident][3]bool{},
+		ReadStages:                map[ObjectID]map[Privilege]bool{},
+		WriteStages:               map[ObjectID]map[Privilege]bool{},
+		matchedAccountObjects:     map[semantics.ObjExpr]*matchedAccountObjs{},
+		existingMaskingPolicies:   map[semantics.Ident]MaskingPolicy{},
+		existingRowAccessPolicies: map[semantics.Ident]RowAccessPolicy{},
+		UserGroupColumn:           semantics.ColExprs{},
 	}
 
+	for e, ea := range pSem.UserGroupColumn.ColExprs {
+		if ea.DTAP == "" || ea.DTAP == pdID.DTAP {
+			pd.UserGroupColumn[e] = ea
+		}
+	}
+
+	// Interfaces are not created in hidden dtaps, nor when they are retired, so neither are their database roles
 	for id, iSem := range pSem.Interfaces {
-		pd.Interfaces[id] = NewInterface(pd.DTAP, iSem, userGroupMappings[pSem.UserGroupMappingID])
+		if iSem.IsHidden(pd.DTAP) || iSem.Lifecycle == semantics.LifecycleRetired {
+			continue
+		}
+		pd.Interfaces[id] = NewInterface(pd.DTAP, iSem, userGroupMappings[pSem.UserGroupMappingID], classes)
+		if iSem.IsSunset(now) {
+			// Deprecated, and past its sunset date: we revoke its database roles from consumers
+			pd.Interfaces[id].ConsumedBy = map[semantics.ProductDTAPID]struct{}{}
+		}
 	}
 
 	for iid, dtapMapping := range pSem.Consumes {
@@ -76,6 +124,7 @@ func NewProductDTAP(pdID semantics.ProductDTAPID, isProd bool, pSem semantics.Pr
 	for k := range pd.Interface.ObjectMatchers {
 		pd.matchedAccountObjects[k] = &matchedAccountObjs{}
 	}
+	pd.setLoadColumns()
 
 	// Set which service account users we should grant the write role to.
 	for _, svc := range svcs {
@@ -87,16 +136,23 @@ func NewProductDTAP(pdID semantics.ProductDTAPID, isProd bool, pSem semantics.Pr
 	}
 
 	// Set which personal users we should grant the read and write roles to.
+	// Members of teams that are on call for the product are granted the operate role
+	// Members whose membership has not started yet, or has ended, are skipped, so their grants will be revoked
 	for _, team := range teams {
+		if _, ok := team.OnCallFor[pd.ProductID]; ok && !(team.OnlyNonProd && pd.IsProd) {
+			for ident := range team.ActiveMembers(now) {
+				pd.GrantOperateRoleToUsers[ident] = false // no GRANT found in Snowflake yet
+			}
+		}
 		if _, ok := team.WorkOn[pd.ProductID]; team.IsCentral && !pd.BlockCentralTeams || ok {
 			if team.OnlyNonProd && pd.IsProd {
 				continue
 			}
-			for ident := range team.Members {
+			for ident := range team.ActiveMembers(now) {
 				pd.GrantReadRoleToUsers[ident] = false // no GRANT found in Snowflake yet
 			}
 			if pd.IsManual {
-				for ident := range team.Members {
+				for ident := range team.ActiveMembers(now) {
 					pd.GrantWriteRoleToUsers[ident] = false // no GRANT found in Snowflake yet
 				}
 			}
@@ -106,11 +162,30 @@ func NewProductDTAP(pdID semantics.ProductDTAPID, isProd bool, pSem semantics.Pr
 	return pd
 }
 
+func (pd *ProductDTAP) setLoadColumns() {
+	pd.loadColumns = map[semantics.ObjExpr]bool{}
+	colExprs := []semantics.ColExprs{pd.UserGroupColumn}
+	for _, i := range pd.Interfaces {
+		colExprs = append(colExprs, i.MaskColumns, i.HashColumns)
+	}
+	for e := range pd.Interface.ObjectMatchers {
+		for _, m := range colExprs {
+			for c := range m {
+				if !c.DisjointWithObjExpr(e) {
+					pd.loadColumns[e] = true
+				}
+			}
+		}
+	}
+}
+
 func NewZombieProductDTAP(pdID semantics.ProductDTAPID) *ProductDTAP {
 	return &ProductDTAP{
-		ProductDTAPID: pdID,
-		Interface:     &Interface{},
-		isZombie:      true,
+		ProductDTAPID:             pdID,
+		Interface:                 &Interface{},
+		isZombie:                  true,
+		existingMaskingPolicies:   map[semantics.Ident]MaskingPolicy{},
+		existingRowAccessPolicies: map[semantics.Ident]RowAccessPolicy{},
 	}
 }
 
@@ -132,6 +207,14 @@ func (pd *ProductDTAP) createProductRoles(ctx context.Context, semCnf *semantics
 		}
 	}
 
+	// Operate role, idem
+	pd.OperateRole = newProductRole(semCnf, pd.ProductID, pd.DTAP, ModeOperate)
+	if _, ok := productRoles[pd.OperateRole]; !ok {
+		if err := pd.OperateRole.Create(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -146,19 +229,37 @@ func (pd *ProductDTAP) setWriteRoleGrantedToUserManagedRoles(ctx context.Context
 			return err
 		}
 		if strings.HasPrefix(string(g.GrantedToName), string(semCnf.Prefix)) {
-			return fmt.Errorf("product dtap write role '%s' granted to other grupr managed role, please take action to correct")
+			return fmt.Errorf("product dtap write role '%s' granted to other grupr managed role, please take action to correct", pd.WriteRole.ID)
 		}
 		if slices.Contains(cnf.SystemDefinedRoles, g.GrantedToName) {
 			continue
 		}
 		pd.writeRoleGrantedToUserManagedRoles[g.GrantedToName] = struct{}{}
 	}
+	// Grants made before grupr recorded them, or by hand, count from when we first find them
+	for r := range pd.writeRoleGrantedToUserManagedRoles {
+		if _, ok := pd.legacyOwnerGrants[r]; !ok {
+			if err := recordLegacyOwnerGrant(ctx, cnf, conn, pd.ProductDTAPID, r); err != nil {
+				return err
+			}
+			pd.legacyOwnerGrants[r] = time.Now()
+		}
+	}
+	// And grants that have been revoked since, e.g., by sysadmins, no longer need to be confiscated
+	for r := range pd.legacyOwnerGrants {
+		if _, ok := pd.writeRoleGrantedToUserManagedRoles[r]; !ok {
+			if err := deleteLegacyOwnerGrant(ctx, cnf, conn, pd.ProductDTAPID, r); err != nil {
+				return err
+			}
+			delete(pd.legacyOwnerGrants, r)
+		}
+	}
 	return nil
 }
 
 func (pd *ProductDTAP) setupProductRoles(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB,
 	productRoles map[ProductRole]struct{}) error {
-	// Create the product roles if necessary (read, write)
+	// Create the product roles if necessary (read, write, operate)
 	if err := pd.createProductRoles(ctx, semCnf, cnf, conn, productRoles); err != nil {
 		return err
 	}
@@ -185,6 +286,20 @@ func (pd *ProductDTAP) grant(ctx context.Context, semCnf *semantics.Config, cnf
 	if err := DoGrantsSkipErrors(ctx, cnf, conn, pd.getToDoWarehouseGrants()); err != nil {
 		return err
 	}
+	// And on stages, idem; see product_dtap__stages.go
+	if err := pd.setStageGrants(ctx, cnf, conn, productRoles); err != nil {
+		return err
+	}
+	if err := DoGrantsSkipErrors(ctx, cnf, conn, pd.getToDoStageGrants()); err != nil {
+		return err
+	}
+	// The operate role inherits the privileges of the read role; see product_dtap__operate.go
+	if err := pd.setReadRoleGrantedToOperateRole(ctx, cnf, conn, productRoles); err != nil {
+		return err
+	}
+	if err := DoGrants(ctx, cnf, conn, pd.getToDoGrantsOfReadRoleToOperateRole()); err != nil {
+		return err
+	}
 
 	// We handle grants on objects like databases, schemas, tables, and
 	// views, that may be created or dropped concurrently
@@ -234,13 +349,19 @@ func (pd *ProductDTAP) pushToDoDBRoleGrants(yield func(Grant) bool, doProd bool,
 	return true
 }
 
+func (pd *ProductDTAP) getGrantRoleToUsers(m Mode) map[semantics.Ident]bool {
+	switch m {
+	case ModeWrite:
+		return pd.GrantWriteRoleToUsers
+	case ModeOperate:
+		return pd.GrantOperateRoleToUsers
+	}
+	return pd.GrantReadRoleToUsers
+}
+
 func (pd *ProductDTAP) pushToDoProductRoleGrants(yield func(Grant) bool) bool {
-	for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
-		m := pd.GrantReadRoleToUsers
-		if pr.Mode == ModeWrite {
-			m = pd.GrantWriteRoleToUsers
-		}
-		for ident, alreadyGranted := range m {
+	for _, pr := range [3]ProductRole{pd.ReadRole, pd.WriteRole, pd.OperateRole} {
+		for ident, alreadyGranted := range pd.getGrantRoleToUsers(pr.Mode) {
 			if !alreadyGranted {
 				if !yield(Grant{
 					Privileges:    []PrivilegeComplete{PrivilegeComplete{Privilege: PrvUsage}},
@@ -258,14 +379,11 @@ func (pd *ProductDTAP) pushToDoProductRoleGrants(yield func(Grant) bool) bool {
 }
 
 func (pd *ProductDTAP) setGrantedUsers(ctx context.Context, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{}) error {
-	for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
+	for _, pr := range [3]ProductRole{pd.ReadRole, pd.WriteRole, pd.OperateRole} {
 		if _, ok := productRoles[pr]; !ok && cnf.DryRun {
 			continue
 		}
-		m := pd.GrantReadRoleToUsers
-		if pr.Mode == ModeWrite {
-			m = pd.GrantWriteRoleToUsers
-		}
+		m := pd.getGrantRoleToUsers(pr.Mode)
 		for grant, err := range QueryGrantsOfRoleToUsers(ctx, conn, pr.ID) {
 			if err != nil {
 				return err
@@ -280,16 +398,65 @@ func (pd *ProductDTAP) setGrantedUsers(ctx context.Context, cnf *Config, conn *s
 	return nil
 }
 
+func (pd *ProductDTAP) getProductRole(m Mode) ProductRole {
+	switch m {
+	case ModeWrite:
+		return pd.WriteRole
+	case ModeOperate:
+		return pd.OperateRole
+	}
+	return pd.ReadRole
+}
+
+func (pd *ProductDTAP) addBreakGlass(b BreakGlass, now time.Time) {
+	if !now.Before(b.ExpiresAt) {
+		// No longer declaring the grant is enough for it to be revoked; we keep it around to record the revocation
+		pd.expiredBreakGlass = append(pd.expiredBreakGlass, b)
+		return
+	}
+	m := pd.getGrantRoleToUsers(b.Mode)
+	if _, ok := m[b.User]; !ok {
+		m[b.User] = false // no GRANT found in Snowflake yet
+	}
+	pd.breakGlass = append(pd.breakGlass, b)
+}
+
+// recordBreakGlass records break glass grants that are about to be done, and those that are about to be revoked,
+// because they expired; it should be called after setGrantedUsers
+func (pd *ProductDTAP) recordBreakGlass(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	for _, b := range pd.breakGlass {
+		if !pd.getGrantRoleToUsers(b.Mode)[b.User] {
+			log.Printf("Activating %v, reason: '%s', expires at %v", b, b.Reason, b.ExpiresAt)
+			if err := b.record(ctx, cnf, conn, "activated"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, b := range pd.expiredBreakGlass {
+		role := pd.getProductRole(b.Mode)
+		for _, g := range pd.toRevoke {
+			if g.GrantedTo == ObjTpUser && g.GrantedRole == role.ID && g.GrantedToName == b.User {
+				log.Printf("Revoking %v, it expired at %v", b, b.ExpiresAt)
+				if err := b.record(ctx, cnf, conn, "expired"); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
 func (pd *ProductDTAP) revokeGrantFromProductRole(g Grant) {
 	pd.toRevoke = append(pd.toRevoke, g)
 }
 
 func (pd *ProductDTAP) revoke(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{},
 	grupinDisjointFromObject func(semantics.Ident, semantics.Ident, semantics.Ident) bool,
-	userManagedOwners func(semantics.ProductDTAPID) map[semantics.Ident]struct{}, c *accountCache) error {
+	userManagedOwners func(semantics.ProductDTAPID) map[semantics.Ident]struct{}, grupinTags func(ObjectID) bool, c *accountCache) error {
 	// We skip does-not-exist errors here, because:
 	// - Users that do not exist are already revoked
-	// - Warehouses that do not exist are already revoked
+	// - Warehouses and stages that do not exist are already revoked
 	// - DB roles that do not exist: it won't help refreshing just this product to refresh this info: a program re-run would be needed
 	if err := DoRevokesSkipErrors(ctx, cnf, conn, slices.Values(pd.toRevoke)); err != nil {
 		return err
@@ -315,15 +482,19 @@ func (pd *ProductDTAP) revoke(ctx context.Context, semCnf *semantics.Config, cnf
 	//   we would need to grant now. Rather than ignoring that signal and wait for the
 	//   next entire Grupr run, it can save our DBAs some time if we act immediately and
 	//   refresh just this single data product-dtap.
-	err := pd.revoke_(ctx, cnf, conn)
+	err := pd.revoke_(ctx, cnf, conn, grupinTags)
 	for err == ErrObjectNotExistOrAuthorized {
 		err = pd.grant_(ctx, semCnf, cnf, conn, productRoles, grupinDisjointFromObject, userManagedOwners, c)
 		if err != nil {
 			continue
 		}
-		err = pd.revoke_(ctx, cnf, conn)
+		err = pd.revoke_(ctx, cnf, conn, grupinTags)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	// Finally, revoke the write role from legacy owners after the grace period; see legacy_owner.go
+	return pd.confiscate(ctx, cnf, conn, time.Now())
 }
 
 func (pd *ProductDTAP) dropProductRolesIfZombie(ctx context.Context, cnf *Config, conn *sql.DB) error {
@@ -334,6 +505,10 @@ func (pd *ProductDTAP) dropProductRolesIfZombie(ctx context.Context, cnf *Config
 		log.Printf("WARN: product '%s', dtap '%s', has ownership of objects, not dropping product roles", pd.ProductID, pd.DTAP)
 		return nil
 	}
+	// Drop the operate role first, it has been granted the read role
+	if err := pd.OperateRole.Drop(ctx, cnf, conn); err != nil {
+		return err
+	}
 	if err := pd.ReadRole.Drop(ctx, cnf, conn); err != nil {
 		return err
 	}
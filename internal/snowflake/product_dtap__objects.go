@@ -8,6 +8,7 @@ import (
 	"log"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/rwberendsen/grupr/internal/semantics"
 	"github.com/rwberendsen/grupr/internal/util"
@@ -21,7 +22,7 @@ func (pd *ProductDTAP) refresh(ctx context.Context, semCnf *semantics.Config, cn
 	if err := pd.refresh_(ctx, semCnf, cnf, conn, c); err != nil {
 		return err
 	}
-	pd.recalcObjects() // will reset all accountObjs
+	pd.recalcObjects(cnf) // will reset all accountObjs
 	// Reset other properties of pd that depend on which objects where matched
 	pd.toRevokeFutureObjects = []FutureGrant{}
 	pd.toRevokeObjects = []Grant{}
@@ -44,19 +45,22 @@ func (pd *ProductDTAP) refresh_(ctx context.Context, semCnf *semantics.Config, c
 
 func (pd *ProductDTAP) refreshObjExprs(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, c *accountCache) error {
 	for e, om := range pd.ObjectMatchers {
-		if err := c.match(ctx, semCnf, cnf, conn, om, pd.matchedAccountObjects[e]); err != nil {
+		if err := c.match(ctx, semCnf, cnf, conn, om, pd.loadColumns[e], pd.matchedAccountObjects[e]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (pd *ProductDTAP) recalcObjects() {
+func (pd *ProductDTAP) recalcObjects(cnf *Config) {
 	pd.Interface.recalcObjectsFromMatched(pd.matchedAccountObjects)
 	for _, v := range pd.Interfaces {
 		v.recalcObjects(pd.accountObjects)
 		v.aggregate() // this will free memory held by AccountObjs by ObjExpr
 	}
+	if cnf.ManageTags {
+		pd.Interface.setUserGroupsByObject()
+	}
 	pd.Interface.aggregate() // we needed to hold on to AccountObjs by ObjExpr until we derived all interface objects
 }
 
@@ -113,7 +117,7 @@ func (pd *ProductDTAP) setGrantsToWriteRole(ctx context.Context, cnf *Config, co
 	if _, ok := productRoles[pd.WriteRole]; !ok && cnf.DryRun {
 		return nil
 	}
-	for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pd.WriteRole.ID, map[GrantTemplate]struct{}{
+	match := map[GrantTemplate]struct{}{
 		GrantTemplate{
 			PrivilegeComplete: PrivilegeComplete{Privilege: PrvCreate, CreateObjectType: ObjTpTable},
 			GrantedOn:         ObjTpSchema,
@@ -122,15 +126,14 @@ func (pd *ProductDTAP) setGrantsToWriteRole(ctx context.Context, cnf *Config, co
 			PrivilegeComplete: PrivilegeComplete{Privilege: PrvCreate, CreateObjectType: ObjTpView},
 			GrantedOn:         ObjTpSchema,
 		}: {},
-		GrantTemplate{
-			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOwnership},
-			GrantedOn:         ObjTpTable,
-		}: {},
-		GrantTemplate{
+	}
+	for _, ot := range objTypesObjectLevel {
+		match[GrantTemplate{
 			PrivilegeComplete: PrivilegeComplete{Privilege: PrvOwnership},
-			GrantedOn:         ObjTpView,
-		}: {},
-	}, nil) {
+			GrantedOn:         ot,
+		}] = struct{}{}
+	}
+	for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pd.WriteRole.ID, match, nil) {
 		if err != nil {
 			return err
 		}
@@ -153,14 +156,15 @@ func (pd *ProductDTAP) setGrantsToWriteRole(ctx context.Context, cnf *Config, co
 			// Ignore; unmanaged grant
 		case PrvOwnership:
 			switch g.GrantedOn {
-			case ObjTpTable, ObjTpView:
-				if !pd.Interface.ObjectMatchers.DisjointFromObject(g.Database, g.Schema, g.Object) {
+			case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+				ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
+				if !pd.Interface.ObjectMatchers.DisjointFromObject(g.Database, g.Schema, objName(g.GrantedOn, g.Object)) {
 					if schemaObjs, ok := pd.Interface.aggAccountObjects.GetSchema(g.Database, g.Schema); ok {
 						if aggObjAttr, ok := schemaObjs.Objects[g.Object]; ok {
 							schemaObjs.Objects[g.Object] = aggObjAttr.setGrantTo(ModeWrite, g)
 						}
 					}
-				} else if grupinDisjointFromObject(g.Database, g.Schema, g.Object) {
+				} else if grupinDisjointFromObject(g.Database, g.Schema, objName(g.GrantedOn, g.Object)) {
 					// There will be no other product claiming ownership of this object, we need to
 					// transfer its ownership to a role that is not managed by grupr.
 					// Note that when we refreshed, toTransferOwnership was reset to an empty slice
@@ -246,16 +250,56 @@ func (pd *ProductDTAP) grant_(ctx context.Context, semCnf *semantics.Config, cnf
 	// Before we actually grant ownership to the write role, grant the write role itself to all the current owners of the objects
 	// of interest. This way, they will not lose ownership, in fact, they will not lose any privilege, and running grupr will
 	// not mess up any other processes that may be running.
-	// We never revoke from any role; that is the job of sysadmins: when they are done with those roles, they can drop them,
-	// or if the roles need to be retained for other purposes, they can revoke this product dtap role from that other role.
+	// We do not revoke from these roles right away; that is the job of sysadmins: when they are done with those roles, they
+	// can drop them, or if the roles need to be retained for other purposes, they can revoke this product dtap role from
+	// that other role. Or, after a grace period, grupr can revoke it; see legacy_owner.go
 	if err := pd.setUserManagedOwnersOfObjects(semCnf, cnf, userManagedOwners); err != nil {
 		return err
 	}
 	if err := DoGrants(ctx, cnf, conn, pd.getToDoGrantsOfWriteRoleToUserManagedRoles(semCnf, cnf)); err != nil {
 		return err
 	}
+	// Record when we granted the write role to these legacy owners, so that we can revoke it after a grace period
+	for r := range pd.getToDoGrantsOfWriteRoleToUserManagedRoles(semCnf, cnf) {
+		if _, ok := pd.legacyOwnerGrants[r.GrantedToName]; !ok {
+			if err := recordLegacyOwnerGrant(ctx, cnf, conn, pd.ProductDTAPID, r.GrantedToName); err != nil {
+				return err
+			}
+			pd.legacyOwnerGrants[r.GrantedToName] = time.Now()
+		}
+	}
 	// Then, make a second pass over the objects, and grant ownership to the write role.
-	if err := DoGrantsIndividually(ctx, cnf, conn, pd.getToDoOwnershipGrants()); err != nil {
+	if err := DoOwnershipGrants(ctx, cnf, conn, pd.getToDoOwnershipGrants(), func(g Grant) {
+		schemaObjs, _ := pd.Interface.aggAccountObjects.GetSchema(g.Database, g.Schema)
+		owner := schemaObjs.Objects[g.Object].Owner
+		_, ok := pd.userManagedOwnersOfObjects[owner]
+		pd.addOwnershipTransfer(ctx, cnf, conn, g, owner, ok)
+	}); err != nil {
+		return err
+	}
+
+	// The operate role can monitor pipes and tasks; see product_dtap__operate.go
+	if err := pd.setGrantsToOperateRole(ctx, cnf, conn, productRoles); err != nil {
+		return err
+	}
+	if err := DoGrants(ctx, cnf, conn, pd.getToDoGrantsToOperateRole()); err != nil {
+		return err
+	}
+
+	// Tag the objects of the product dtap; see product_dtap__tags.go for this method and its helper methods
+	if cnf.ManageTags {
+		if err := DoTagRefs(ctx, cnf, conn, pd.getToDoTagRefs(), false); err != nil {
+			return err
+		}
+	}
+
+	// Attach masking and row access policies before granting read privileges, so that consumers will not see
+	// unmasked values or rows of other user groups of newly matched objects.
+	// See product_dtap__policies.go for these methods and their helper methods
+	if err := pd.setMaskingPolicies(ctx, semCnf, cnf, conn); err != nil {
+		return err
+	}
+	if err := pd.setRowAccessPolicies(ctx, semCnf, cnf, conn); err != nil {
 		return err
 	}
 
@@ -288,7 +332,7 @@ func (pd *ProductDTAP) grant_(ctx context.Context, semCnf *semantics.Config, cnf
 	return nil
 }
 
-func (pd *ProductDTAP) revoke_(ctx context.Context, cnf *Config, conn *sql.DB) error {
+func (pd *ProductDTAP) revoke_(ctx context.Context, cnf *Config, conn *sql.DB, grupinTags func(ObjectID) bool) error {
 	// We first revoke write privileges, to stop the wrong roles from creating objects asap
 	// As with read privileges, we start with future privileges
 	if err := DoFutureRevokes(ctx, cnf, conn, slices.Values(pd.toRevokeFutureObjects)); err != nil {
@@ -297,6 +341,12 @@ func (pd *ProductDTAP) revoke_(ctx context.Context, cnf *Config, conn *sql.DB) e
 	if err := DoRevokes(ctx, cnf, conn, slices.Values(pd.toRevokeObjects)); err != nil {
 		return err
 	}
+	// Unset tags on objects that leave the product dtap, before we forget about them when transferring ownership
+	if cnf.ManageTags {
+		if err := DoTagRefs(ctx, cnf, conn, pd.getToDoTagUnsets(grupinTags), true); err != nil {
+			return err
+		}
+	}
 	// Now we transfer ownership of objects that should no longer be owned by Grupr-managed roles
 	// First, we check if we can unambiguously do this. If not, we log a message and do not
 	// transfer ownership; sysadmins need to make some changes in Snowflake first.
@@ -319,7 +369,10 @@ func (pd *ProductDTAP) revoke_(ctx context.Context, cnf *Config, conn *sql.DB) e
 		hasNewOwner = true
 	}
 	if hasNewOwner {
-		if err := DoGrantsIndividually(ctx, cnf, conn, pd.getTransferOwnershipGrants(newOwner)); err != nil {
+		if err := DoOwnershipGrants(ctx, cnf, conn, pd.getTransferOwnershipGrants(newOwner), func(g Grant) {
+			_, ok := pd.writeRoleGrantedToUserManagedRoles[g.GrantedToName]
+			pd.addOwnershipTransfer(ctx, cnf, conn, g, pd.WriteRole.ID, ok)
+		}); err != nil {
 			return err
 		}
 		pd.toTransferOwnership = []Grant{}
@@ -337,6 +390,15 @@ func (pd *ProductDTAP) revoke_(ctx context.Context, cnf *Config, conn *sql.DB) e
 	if err := DoRevokes(ctx, cnf, conn, pd.getToDoRevokes()); err != nil {
 		return err
 	}
+
+	// Finally, detach masking policies from columns that should no longer be masked, and row access policies
+	// from objects that should no longer have their rows filtered
+	if err := pd.unsetMaskingPolicies(ctx, cnf, conn); err != nil {
+		return err
+	}
+	if err := pd.unsetRowAccessPolicies(ctx, cnf, conn); err != nil {
+		return err
+	}
 	return nil
 }
 
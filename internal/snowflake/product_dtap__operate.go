@@ -0,0 +1,114 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+
+	"github.com/rwberendsen/grupr/internal/util"
+)
+
+/*
+In product_dtap__operate.go, we have ProductDTAP methods that deal with the privileges of the operate role, apart from
+those on warehouses, see product_dtap__warehouses.go for those.
+
+The operate role is meant for on-call engineers: it is granted the read role, and it can monitor the pipes and tasks of
+the product dtap, so that they can see what went wrong and restart things, without holding the write role.
+*/
+
+func (pd *ProductDTAP) setReadRoleGrantedToOperateRole(ctx context.Context, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{}) error {
+	if _, ok := productRoles[pd.OperateRole]; !ok && cnf.DryRun {
+		return nil
+	}
+	for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pd.OperateRole.ID, map[GrantTemplate]struct{}{
+		GrantTemplate{
+			PrivilegeComplete:         PrivilegeComplete{Privilege: PrvUsage},
+			GrantedOn:                 ObjTpRole,
+			GrantedRoleIsGruprManaged: util.NewTrue(),
+		}: {},
+	}, nil) {
+		if err != nil {
+			return err
+		}
+		if g.GrantedRole == pd.ReadRole.ID {
+			pd.isReadRoleGrantedToOperateRole = true
+		} else {
+			// The operate role should not inherit any other grupr managed role
+			pd.toRevoke = append(pd.toRevoke, g)
+		}
+	}
+	return nil
+}
+
+func (pd *ProductDTAP) getToDoGrantsOfReadRoleToOperateRole() iter.Seq[Grant] {
+	return func(yield func(Grant) bool) {
+		if !pd.isReadRoleGrantedToOperateRole {
+			yield(Grant{
+				Privileges:    []PrivilegeComplete{PrivilegeComplete{Privilege: PrvUsage}},
+				GrantedOn:     ObjTpRole,
+				GrantedRole:   pd.ReadRole.ID,
+				GrantedTo:     ObjTpRole,
+				GrantedToName: pd.OperateRole.ID,
+			})
+		}
+	}
+}
+
+func (pd *ProductDTAP) setGrantsToOperateRole(ctx context.Context, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{}) error {
+	if _, ok := productRoles[pd.OperateRole]; !ok && cnf.DryRun {
+		return nil
+	}
+	for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pd.OperateRole.ID, map[GrantTemplate]struct{}{
+		GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor},
+			GrantedOn:         ObjTpPipe,
+		}: {},
+		GrantTemplate{
+			PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor},
+			GrantedOn:         ObjTpTask,
+		}: {},
+	}, nil) {
+		if err != nil {
+			return err
+		}
+		if !pd.Interface.ObjectMatchers.DisjointFromObject(g.Database, g.Schema, g.Object) {
+			if schemaObjs, ok := pd.Interface.aggAccountObjects.GetSchema(g.Database, g.Schema); ok {
+				if aggObjAttr, ok := schemaObjs.Objects[g.Object]; ok {
+					schemaObjs.Objects[g.Object] = aggObjAttr.setGrantTo(ModeOperate, g)
+				}
+			}
+			// ignore, we did not match the object last time we refreshed, but the grant is fine, we leave it
+		} else {
+			// Note that when we refreshed, toRevokeObjects was reset to an empty slice
+			pd.toRevokeObjects = append(pd.toRevokeObjects, g)
+		}
+	}
+	return nil
+}
+
+func (pd *ProductDTAP) getToDoGrantsToOperateRole() iter.Seq[Grant] {
+	return func(yield func(Grant) bool) {
+		for db, dbObjs := range pd.Interface.aggAccountObjects.DBs {
+			for schema, schemaObjs := range dbObjs.Schemas {
+				for obj, objAttr := range schemaObjs.Objects {
+					switch objAttr.ObjectType {
+					case ObjTpPipe, ObjTpTask:
+						if !objAttr.hasGrantTo(ModeOperate, PrvMonitor) {
+							if !yield(Grant{
+								Privileges:    []PrivilegeComplete{PrivilegeComplete{Privilege: PrvMonitor}},
+								GrantedOn:     objAttr.ObjectType,
+								Database:      db,
+								Schema:        schema,
+								Object:        obj,
+								GrantedTo:     ObjTpRole,
+								GrantedToName: pd.OperateRole.ID,
+							}) {
+								return
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
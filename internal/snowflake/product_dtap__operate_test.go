@@ -0,0 +1,44 @@
+package snowflake
+
+import (
+	"testing"
+)
+
+func TestOperateGrantTemplates(t *testing.T) {
+	tests := []struct {
+		template GrantTemplate
+		name     string
+		filter   string
+		grant    string
+	}{
+		{
+			template: GrantTemplate{PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor}, GrantedOn: ObjTpPipe},
+			name:     `DB.SCHEMA.MY_PIPE`,
+			filter:   `privilege = 'MONITOR' AND granted_on = 'PIPE'`,
+			grant:    `GRANT MONITOR ON PIPE IDENTIFIER($$"DB"."SCHEMA"."MY_PIPE"$$) TO ROLE IDENTIFIER($$"OPERATE_ROLE"$$)`,
+		},
+		{
+			template: GrantTemplate{PrivilegeComplete: PrivilegeComplete{Privilege: PrvMonitor}, GrantedOn: ObjTpTask},
+			name:     `DB.SCHEMA."my task"`,
+			filter:   `privilege = 'MONITOR' AND granted_on = 'TASK'`,
+			grant:    `GRANT MONITOR ON TASK IDENTIFIER($$"DB"."SCHEMA"."my task"$$) TO ROLE IDENTIFIER($$"OPERATE_ROLE"$$)`,
+		},
+	}
+	for _, test := range tests {
+		if got, _ := test.template.buildSQLFilter(); got != test.filter {
+			t.Errorf("buildSQLFilter(%v) = %q, want %q", test.template, got, test.filter)
+		}
+		// As the grant is listed by SHOW GRANTS TO ROLE
+		g, err := newGrantToRole(test.template.Privilege.String(), "", test.template.GrantedOn.String(), test.name, nil, ObjTpRole, "", "OPERATE_ROLE", false, "")
+		if err != nil {
+			t.Errorf("newGrantToRole(%v, %q): %v", test.template, test.name, err)
+			continue
+		}
+		if g.GrantedOn != test.template.GrantedOn || len(g.Privileges) != 1 || g.Privileges[0] != test.template.PrivilegeComplete {
+			t.Errorf("newGrantToRole(%v, %q): does not match template: %v", test.template, test.name, g)
+		}
+		if got := g.buildSQLGrant(false); got != test.grant {
+			t.Errorf("buildSQLGrant() = %q, want %q", got, test.grant)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"log"
+	"maps"
+	"slices"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+/*
+In product_dtap__policies.go, we have ProductDTAP methods that deal with policies on columns of objects, like masking policies,
+which may also hash values, and row access policies
+*/
+
+func reportUnmatchedColExprs(pdID semantics.ProductDTAPID, iid string, name string, colExprs semantics.ColExprs,
+	matched map[semantics.ColExpr]bool) {
+	for e := range colExprs {
+		if !matched[e] {
+			log.Printf("WARN: product '%s', dtap '%s', interface '%s': %s expression '%v' matches no column\n",
+				pdID.ProductID, pdID.DTAP, iid, name, e)
+		}
+	}
+}
+
+func (pd *ProductDTAP) setMaskedColumns(semCnf *semantics.Config, cnf *Config) {
+	// (re)set
+	pd.maskingPolicies = map[semantics.Ident]MaskingPolicy{}
+	pd.maskedColumns = map[ColumnID]PolicyRef{}
+
+	// A column can be masked or hashed in multiple interfaces, each having their own consumers
+	maskedFor := map[ColumnID]map[semantics.ProductDTAPID]struct{}{}
+	hashedFor := map[ColumnID]map[semantics.ProductDTAPID]struct{}{}
+	refs := map[ColumnID]PolicyRef{}
+	dataTypes := map[ColumnID]string{}
+	for iid, i := range pd.Interfaces {
+		if len(i.ConsumedBy) == 0 {
+			continue
+		}
+		for _, directive := range [2]struct {
+			name     string
+			colExprs semantics.ColExprs
+			m        map[ColumnID]map[semantics.ProductDTAPID]struct{}
+			matched  map[semantics.ColExpr]bool
+		}{{"mask_columns", i.MaskColumns, maskedFor, map[semantics.ColExpr]bool{}},
+			{"hash_columns", i.HashColumns, hashedFor, map[semantics.ColExpr]bool{}}} {
+			for db, dbObjs := range i.aggAccountObjects.DBs {
+				for schema, schemaObjs := range dbObjs.Schemas {
+					for obj, objAttr := range schemaObjs.Objects {
+						for e := range directive.colExprs {
+							if !e.MatchObject(db, schema, obj) {
+								continue
+							}
+							for _, col := range objAttr.Columns {
+								if !e.Column().Match(col.Name) {
+									continue
+								}
+								directive.matched[e] = true
+								id := ColumnID{Database: db, Schema: schema, Object: obj, Column: col.Name}
+								if _, ok := directive.m[id]; !ok {
+									directive.m[id] = map[semantics.ProductDTAPID]struct{}{}
+								}
+								for pdID := range i.ConsumedBy {
+									directive.m[id][pdID] = struct{}{}
+								}
+								refs[id] = PolicyRef{
+									PolicyKind:     ObjTpMaskingPolicy,
+									PolicyDatabase: cnf.Database,
+									PolicySchema:   cnf.Schema,
+									ObjectType:     objAttr.ObjectType,
+									Database:       db,
+									Schema:         schema,
+									Object:         obj,
+									Column:         col.Name,
+								}
+								dataTypes[id] = col.DataType
+							}
+						}
+					}
+				}
+			}
+			reportUnmatchedColExprs(pd.ProductDTAPID, iid, directive.name, directive.colExprs, directive.matched)
+		}
+	}
+	for id, r := range refs {
+		if len(hashedFor[id]) > 0 && !canHash(dataTypes[id]) {
+			log.Printf("WARN: product '%s', dtap '%s': column %v.%v.%v.%v of type %s can not be hashed, masking it instead\n",
+				pd.ProductID, pd.DTAP, id.Database, id.Schema, id.Object, id.Column, dataTypes[id])
+			if _, ok := maskedFor[id]; !ok {
+				maskedFor[id] = map[semantics.ProductDTAPID]struct{}{}
+			}
+			for pdID := range hashedFor[id] {
+				maskedFor[id][pdID] = struct{}{}
+			}
+			delete(hashedFor, id)
+		}
+		p := newMaskingPolicy(semCnf, cnf, pd.ProductDTAPID, dataTypes[id], maskedFor[id], hashedFor[id])
+		pd.maskingPolicies[p.Name] = p
+		r.Policy = p.Name
+		pd.maskedColumns[id] = r
+	}
+}
+
+func (pd *ProductDTAP) hasHashColumns() bool {
+	for _, i := range pd.Interfaces {
+		if len(i.HashColumns) > 0 && len(i.ConsumedBy) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (pd *ProductDTAP) setMaskingPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	pd.setMaskedColumns(semCnf, cnf)
+	for name, p := range pd.maskingPolicies {
+		if _, ok := pd.existingMaskingPolicies[name]; !ok {
+			if err := p.Create(ctx, cnf, conn); err != nil {
+				return err
+			}
+			if !cnf.DryRun {
+				pd.existingMaskingPolicies[name] = p
+			}
+		}
+	}
+	// Find out which columns already have the right policy attached
+	isDone := map[ColumnID]bool{}
+	for name, p := range pd.maskingPolicies {
+		if _, ok := pd.existingMaskingPolicies[name]; !ok {
+			continue // dry run
+		}
+		for r, err := range p.QueryRefs(ctx, conn) {
+			if err != nil {
+				return err
+			}
+			if want, ok := pd.maskedColumns[r.ColumnID()]; ok && want.Policy == name {
+				isDone[r.ColumnID()] = true
+			}
+		}
+	}
+	return DoPolicyRefs(ctx, cnf, conn, pd.getToDoMaskingPolicyRefs(isDone), false)
+}
+
+func (pd *ProductDTAP) getToDoMaskingPolicyRefs(isDone map[ColumnID]bool) iter.Seq[PolicyRef] {
+	return func(yield func(PolicyRef) bool) {
+		for id, r := range pd.maskedColumns {
+			if !isDone[id] {
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (pd *ProductDTAP) unsetMaskingPolicies(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Columns that should be masked with another policy have already been set, forcing the other
+	// policy to be replaced. Here we only deal with columns that should no longer be masked at all.
+	toUnset := []PolicyRef{}
+	for _, p := range pd.existingMaskingPolicies {
+		for r, err := range p.QueryRefs(ctx, conn) {
+			if err != nil {
+				return err
+			}
+			if _, ok := pd.maskedColumns[r.ColumnID()]; !ok {
+				toUnset = append(toUnset, r)
+			}
+		}
+	}
+	return DoPolicyRefs(ctx, cnf, conn, slices.Values(toUnset), true)
+}
+
+func (pd *ProductDTAP) setRowAccessObjects(semCnf *semantics.Config, cnf *Config) {
+	// (re)set
+	pd.rowAccessPolicies = map[semantics.Ident]RowAccessPolicy{}
+	pd.rowAccessObjects = map[ObjectID]PolicyRef{}
+	if len(pd.UserGroupColumn) == 0 {
+		return
+	}
+	matched := map[semantics.ColExpr]bool{}
+	for db, dbObjs := range pd.Interface.aggAccountObjects.DBs {
+		for schema, schemaObjs := range dbObjs.Schemas {
+			for obj, objAttr := range schemaObjs.Objects {
+				for e := range pd.UserGroupColumn {
+					if !e.MatchObject(db, schema, obj) {
+						continue
+					}
+					for _, col := range objAttr.Columns {
+						if !e.Column().Match(col.Name) {
+							continue
+						}
+						matched[e] = true
+						if col.DataType != "VARCHAR" {
+							log.Printf("WARN: product '%s', dtap '%s': user group column %v.%v.%v.%v is not of type VARCHAR, skipping row access policy\n",
+								pd.ProductID, pd.DTAP, db, schema, obj, col.Name)
+							continue
+						}
+						unrestricted, restricted := pd.getUserGroupsByConsumer(db, schema, obj)
+						if len(restricted) == 0 {
+							continue // no consumer needs to have its rows filtered
+						}
+						p := newRowAccessPolicy(semCnf, cnf, pd.ProductDTAPID, unrestricted, restricted)
+						pd.rowAccessPolicies[p.Name] = p
+						pd.rowAccessObjects[ObjectID{Database: db, Schema: schema, Object: obj}] = PolicyRef{
+							PolicyKind:     ObjTpRowAccessPolicy,
+							PolicyDatabase: cnf.Database,
+							PolicySchema:   cnf.Schema,
+							Policy:         p.Name,
+							ObjectType:     objAttr.ObjectType,
+							Database:       db,
+							Schema:         schema,
+							Object:         obj,
+							Column:         col.Name,
+						}
+						break // only a single row access policy can be attached to an object
+					}
+				}
+			}
+		}
+	}
+	reportUnmatchedColExprs(pd.ProductDTAPID, "", "user_group_column", pd.UserGroupColumn, matched)
+}
+
+func (pd *ProductDTAP) getUserGroupsByConsumer(db semantics.Ident, schema semantics.Ident, obj semantics.Ident) (
+	unrestricted map[semantics.ProductDTAPID]struct{}, restricted map[semantics.ProductDTAPID]map[string]struct{}) {
+	// Values in the user group column are user groups as they are named in the product, we map them
+	// to global user groups to find out which values consumers of an interface are allowed to see.
+	unrestricted = map[semantics.ProductDTAPID]struct{}{}
+	restricted = map[semantics.ProductDTAPID]map[string]struct{}{}
+	for _, i := range pd.Interfaces {
+		if _, ok := i.aggAccountObjects.GetObject(db, schema, obj); !ok {
+			continue
+		}
+		if i.GlobalUserGroups == nil || maps.Equal(i.GlobalUserGroups, pd.Interface.GlobalUserGroups) {
+			for pdID := range i.ConsumedBy {
+				unrestricted[pdID] = struct{}{}
+			}
+			continue
+		}
+		for pdID := range i.ConsumedBy {
+			if _, ok := restricted[pdID]; !ok {
+				restricted[pdID] = map[string]struct{}{}
+			}
+			for local, global := range i.UserGroupMapping {
+				if _, ok := i.GlobalUserGroups[global]; ok {
+					restricted[pdID][local] = struct{}{}
+				}
+			}
+		}
+	}
+	return
+}
+
+func (pd *ProductDTAP) setRowAccessPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) error {
+	pd.setRowAccessObjects(semCnf, cnf)
+	for name, p := range pd.rowAccessPolicies {
+		if _, ok := pd.existingRowAccessPolicies[name]; !ok {
+			if err := p.Create(ctx, cnf, conn); err != nil {
+				return err
+			}
+			if !cnf.DryRun {
+				pd.existingRowAccessPolicies[name] = p
+			}
+		}
+	}
+	// Find out which objects already have the right policy attached, or another policy of this product dtap
+	isDone := map[ObjectID]bool{}
+	replaces := map[ObjectID]semantics.Ident{}
+	for _, p := range pd.existingRowAccessPolicies {
+		for r, err := range p.QueryRefs(ctx, conn) {
+			if err != nil {
+				return err
+			}
+			if want, ok := pd.rowAccessObjects[r.ObjectID()]; ok {
+				if want.Policy == r.Policy && want.Column == r.Column {
+					isDone[r.ObjectID()] = true
+				} else {
+					replaces[r.ObjectID()] = r.Policy
+				}
+			}
+		}
+	}
+	return DoPolicyRefsIndividually(ctx, cnf, conn, pd.getToDoRowAccessPolicyRefs(isDone, replaces), false)
+}
+
+func (pd *ProductDTAP) getToDoRowAccessPolicyRefs(isDone map[ObjectID]bool, replaces map[ObjectID]semantics.Ident) iter.Seq[PolicyRef] {
+	return func(yield func(PolicyRef) bool) {
+		for id, r := range pd.rowAccessObjects {
+			if !isDone[id] {
+				r.ReplacesPolicy = replaces[id]
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (pd *ProductDTAP) unsetRowAccessPolicies(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Objects that should have another policy have already been dealt with, we only drop policies from objects that
+	// should no longer have one at all.
+	toUnset := []PolicyRef{}
+	for _, p := range pd.existingRowAccessPolicies {
+		for r, err := range p.QueryRefs(ctx, conn) {
+			if err != nil {
+				return err
+			}
+			if _, ok := pd.rowAccessObjects[r.ObjectID()]; !ok {
+				toUnset = append(toUnset, r)
+			}
+		}
+	}
+	return DoPolicyRefs(ctx, cnf, conn, slices.Values(toUnset), true)
+}
@@ -0,0 +1,87 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+/*
+In product_dtap__stages.go, we have ProductDTAP methods that deal with (privileges on) stages
+*/
+
+func (pd *ProductDTAP) getStages(m Mode) map[ObjectID]map[Privilege]bool {
+	if m == ModeWrite {
+		return pd.WriteStages
+	}
+	return pd.ReadStages
+}
+
+func (pd *ProductDTAP) addStage(m Mode, id ObjectID, external bool) {
+	prvs := map[Privilege]bool{}
+	for _, p := range getPrivilegesStage(m, external) {
+		prvs[p] = false
+	}
+	pd.getStages(m)[id] = prvs
+}
+
+func (pd *ProductDTAP) setStageGrants(ctx context.Context, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{}) error {
+	for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
+		if _, ok := productRoles[pr]; !ok && cnf.DryRun {
+			continue
+		}
+		match := map[GrantTemplate]struct{}{}
+		for _, p := range [3]Privilege{PrvRead, PrvWrite, PrvUsage} {
+			match[GrantTemplate{
+				PrivilegeComplete: PrivilegeComplete{Privilege: p},
+				GrantedOn:         ObjTpStage,
+			}] = struct{}{}
+		}
+		stages := pd.getStages(pr.Mode)
+		for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pr.ID, match, nil) {
+			if err != nil {
+				return err
+			}
+			// Should we have this grant?
+			if prvs, ok := stages[ObjectID{Database: g.Database, Schema: g.Schema, Object: g.Object}]; ok {
+				if _, ok := prvs[g.Privileges[0].Privilege]; ok {
+					// If yes, mark it as already granted
+					prvs[g.Privileges[0].Privilege] = true
+					continue
+				}
+			}
+			// If not, add it to a list of grants to be revoked
+			pd.toRevoke = append(pd.toRevoke, g)
+		}
+	}
+	return nil
+}
+
+func (pd *ProductDTAP) getToDoStageGrants() iter.Seq[Grant] {
+	return func(yield func(Grant) bool) {
+		for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
+			for id, granted := range pd.getStages(pr.Mode) {
+				prvs := []PrivilegeComplete{}
+				// READ goes before WRITE
+				for _, p := range [3]Privilege{PrvRead, PrvWrite, PrvUsage} {
+					if isGranted, ok := granted[p]; ok && !isGranted {
+						prvs = append(prvs, PrivilegeComplete{Privilege: p})
+					}
+				}
+				if len(prvs) > 0 {
+					if !yield(Grant{
+						Privileges:    prvs,
+						GrantedOn:     ObjTpStage,
+						Database:      id.Database,
+						Schema:        id.Schema,
+						Object:        id.Object,
+						GrantedTo:     ObjTpRole,
+						GrantedToName: pr.ID,
+					}) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
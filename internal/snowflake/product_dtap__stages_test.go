@@ -0,0 +1,98 @@
+package snowflake
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetPrivilegesStage(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		external bool
+		want     []Privilege
+	}{
+		{mode: ModeRead, want: []Privilege{PrvRead}},
+		{mode: ModeWrite, want: []Privilege{PrvRead, PrvWrite}},
+		{mode: ModeRead, external: true, want: []Privilege{PrvUsage}},
+		{mode: ModeWrite, external: true, want: []Privilege{PrvUsage}},
+	}
+	for _, test := range tests {
+		if got := getPrivilegesStage(test.mode, test.external); !slices.Equal(got, test.want) {
+			t.Errorf("getPrivilegesStage(%v, %v) = %v, want %v", test.mode, test.external, got, test.want)
+		}
+	}
+}
+
+func TestStageGrants(t *testing.T) {
+	internal := ObjectID{Database: "DB", Schema: "SCHEMA", Object: "INTERNAL"}
+	external := ObjectID{Database: "DB", Schema: "SCHEMA", Object: "EXTERNAL"}
+	tests := []struct {
+		granted []string // grants found in Snowflake, as SHOW GRANTS lists them: privilege, granted_on, name, grantee
+		want    []string
+	}{
+		{
+			want: []string{
+				`GRANT READ ON STAGE IDENTIFIER($$"DB"."SCHEMA"."INTERNAL"$$) TO ROLE IDENTIFIER($$"READ_ROLE"$$)`,
+				`GRANT USAGE ON STAGE IDENTIFIER($$"DB"."SCHEMA"."EXTERNAL"$$) TO ROLE IDENTIFIER($$"READ_ROLE"$$)`,
+				`GRANT READ, WRITE ON STAGE IDENTIFIER($$"DB"."SCHEMA"."INTERNAL"$$) TO ROLE IDENTIFIER($$"WRITE_ROLE"$$)`,
+			},
+		},
+		{
+			granted: []string{"READ", "STAGE", "DB.SCHEMA.INTERNAL", "WRITE_ROLE"},
+			want: []string{
+				`GRANT READ ON STAGE IDENTIFIER($$"DB"."SCHEMA"."INTERNAL"$$) TO ROLE IDENTIFIER($$"READ_ROLE"$$)`,
+				`GRANT USAGE ON STAGE IDENTIFIER($$"DB"."SCHEMA"."EXTERNAL"$$) TO ROLE IDENTIFIER($$"READ_ROLE"$$)`,
+				`GRANT WRITE ON STAGE IDENTIFIER($$"DB"."SCHEMA"."INTERNAL"$$) TO ROLE IDENTIFIER($$"WRITE_ROLE"$$)`,
+			},
+		},
+		{
+			granted: []string{
+				"READ", "STAGE", "DB.SCHEMA.INTERNAL", "READ_ROLE",
+				"USAGE", "STAGE", "DB.SCHEMA.EXTERNAL", "READ_ROLE",
+				"READ", "STAGE", "DB.SCHEMA.INTERNAL", "WRITE_ROLE",
+				"WRITE", "STAGE", "DB.SCHEMA.INTERNAL", "WRITE_ROLE",
+			},
+			want: []string{},
+		},
+	}
+	for i, test := range tests {
+		pd := &ProductDTAP{
+			ReadRole:    ProductRole{Mode: ModeRead, ID: "READ_ROLE"},
+			WriteRole:   ProductRole{Mode: ModeWrite, ID: "WRITE_ROLE"},
+			ReadStages:  map[ObjectID]map[Privilege]bool{},
+			WriteStages: map[ObjectID]map[Privilege]bool{},
+		}
+		pd.addStage(ModeRead, internal, false)
+		pd.addStage(ModeRead, external, true)
+		pd.addStage(ModeWrite, internal, false)
+		// Mark grants as found, the way setStageGrants does
+		for j := 0; j < len(test.granted); j += 4 {
+			g, err := newGrantToRole(test.granted[j], "", test.granted[j+1], test.granted[j+2], nil, ObjTpRole, "", "", false, "")
+			if err != nil {
+				t.Fatalf("test %d: newGrantToRole: %v", i, err)
+			}
+			m := ModeRead
+			if test.granted[j+3] == "WRITE_ROLE" {
+				m = ModeWrite
+			}
+			prvs, ok := pd.getStages(m)[ObjectID{Database: g.Database, Schema: g.Schema, Object: g.Object}]
+			if !ok {
+				t.Fatalf("test %d: grant %v does not match a stage", i, g)
+			}
+			if _, ok := prvs[g.Privileges[0].Privilege]; !ok {
+				t.Fatalf("test %d: grant %v does not match a privilege of the stage", i, g)
+			}
+			prvs[g.Privileges[0].Privilege] = true
+		}
+		got := []string{}
+		for g := range pd.getToDoStageGrants() {
+			got = append(got, g.buildSQLGrant(false))
+		}
+		slices.Sort(got)
+		want := slices.Clone(test.want)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("test %d: getToDoStageGrants() = %v, want %v", i, got, want)
+		}
+	}
+}
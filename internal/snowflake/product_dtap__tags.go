@@ -0,0 +1,123 @@
+package snowflake
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+/*
+In product_dtap__tags.go, we have ProductDTAP methods that deal with object tags
+*/
+
+func (pd *ProductDTAP) getTagValues(id ObjectID, inInterface func(*Interface) bool) TagValues {
+	v := TagValues{}
+	v[TagProductID] = pd.ProductID
+	v[TagDTAP] = pd.DTAP
+	v[TagUserGroups] = pd.Interface.getUserGroupsStr(id)
+	// Objects that are part of interfaces get the highest classification of those interfaces, which may be lower
+	// than the classification of the product; other objects get the classification of the product.
+	v[TagClassification] = pd.Interface.classificationStr
+	var c semantics.Classification
+	iids := []string{}
+	for _, iid := range slices.Sorted(maps.Keys(pd.Interfaces)) {
+		i := pd.Interfaces[iid]
+		if !inInterface(i) {
+			continue
+		}
+		if len(iids) == 0 || i.Classification > c {
+			c = i.Classification
+			v[TagClassification] = i.classificationStr
+		}
+		iids = append(iids, iid)
+	}
+	v[TagInterfaces] = strings.Join(iids, ",")
+	return v
+}
+
+func (pd *ProductDTAP) getToDoTagRefs() iter.Seq[TagRef] {
+	// Databases and schemas are only tagged if the product dtap matches all of their schemas or objects; otherwise,
+	// they may be shared with other product dtaps.
+	return func(yield func(TagRef) bool) {
+		for db, dbObjs := range pd.Interface.aggAccountObjects.DBs {
+			if dbObjs.MatchAllSchemas {
+				if !yield(TagRef{
+					ObjectType: ObjTpDatabase,
+					Database:   db,
+					Values: pd.getTagValues(ObjectID{Database: db}, func(i *Interface) bool {
+						o, ok := i.aggAccountObjects.DBs[db]
+						return ok && o.MatchAllSchemas
+					}),
+				}) {
+					return
+				}
+			}
+			for schema, schemaObjs := range dbObjs.Schemas {
+				if schemaObjs.MatchAllObjects {
+					if !yield(TagRef{
+						ObjectType: ObjTpSchema,
+						Database:   db,
+						Schema:     schema,
+						Values: pd.getTagValues(ObjectID{Database: db, Schema: schema}, func(i *Interface) bool {
+							o, ok := i.aggAccountObjects.GetSchema(db, schema)
+							return ok && o.MatchAllObjects
+						}),
+					}) {
+						return
+					}
+				}
+				for obj, objAttr := range schemaObjs.Objects {
+					if !yield(TagRef{
+						ObjectType: objAttr.ObjectType,
+						Database:   db,
+						Schema:     schema,
+						Object:     obj,
+						Values: pd.getTagValues(ObjectID{Database: db, Schema: schema, Object: obj}, func(i *Interface) bool {
+							_, ok := i.aggAccountObjects.GetObject(db, schema, obj)
+							return ok
+						}),
+					}) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func (pd *ProductDTAP) getToDoTagUnsets(grupinTags func(ObjectID) bool) iter.Seq[TagRef] {
+	// Objects that leave the product dtap are found among the grants we are about to revoke or transfer. If another
+	// product dtap tags the object, it has done so already, and we leave its tags alone.
+	return func(yield func(TagRef) bool) {
+		seen := map[ObjectID]struct{}{}
+		push := func(t ObjType, id ObjectID) bool {
+			if _, ok := seen[id]; ok || grupinTags(ObjectID{Database: id.Database, Schema: id.Schema, Object: objName(t, id.Object)}) {
+				return true
+			}
+			seen[id] = struct{}{}
+			return yield(TagRef{ObjectType: t, Database: id.Database, Schema: id.Schema, Object: id.Object})
+		}
+		for _, g := range pd.toRevokeFutureObjects {
+			if g.GrantedIn == ObjTpDatabase {
+				if !push(ObjTpDatabase, ObjectID{Database: g.Database}) {
+					return
+				}
+			}
+		}
+		for _, g := range pd.toRevokeObjects {
+			if g.GrantedOn == ObjTpSchema {
+				if !push(ObjTpSchema, ObjectID{Database: g.Database, Schema: g.Schema}) {
+					return
+				}
+			}
+		}
+		for _, g := range pd.toTransferOwnership {
+			if !push(g.GrantedOn, ObjectID{Database: g.Database, Schema: g.Schema, Object: g.Object}) {
+				return
+			}
+		}
+	}
+}
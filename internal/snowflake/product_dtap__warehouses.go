@@ -9,56 +9,65 @@ import (
 )
 
 /*
-In product_dtap__objects.go, we have ProductDTAP methods that deal with (privileges on) warehouses
+In product_dtap__warehouses.go, we have ProductDTAP methods that deal with (privileges on) warehouses
 */
 
-func (pd *ProductDTAP) addWarehouse(m Mode, id semantics.Ident) {
-	switch m {
-	case ModeRead:
-		pd.ReadWarehouses[id] = [2]bool{}
-	case ModeWrite:
-		pd.WriteWarehouses[id] = [2]bool{}
+// getPrivilegesWarehouse returns the privileges a product role of mode m should have on its warehouses
+func getPrivilegesWarehouse(m Mode) [2]Privilege {
+	if m == ModeOperate {
+		// Operators may need to resume or resize a warehouse, and look at its load, but they run queries with the
+		// warehouses granted to the read role
+		return [2]Privilege{PrvOperate, PrvMonitor}
 	}
+	return [2]Privilege{PrvUsage, PrvOperate}
 }
 
-func (pd *ProductDTAP) hasWarehouse(m Mode, id semantics.Ident) bool {
+func (pd *ProductDTAP) getWarehouses(m Mode) map[semantics.Ident][3]bool {
 	switch m {
 	case ModeRead:
-		if _, ok := pd.ReadWarehouses[id]; ok {
-			return true
-		}
+		return pd.ReadWarehouses
 	case ModeWrite:
-		if _, ok := pd.WriteWarehouses[id]; ok {
-			return true
-		}
+		return pd.WriteWarehouses
+	case ModeOperate:
+		return pd.OperateWarehouses
 	}
-	return false
+	return nil
 }
 
-func (pd *ProductDTAP) setWarehouseGrantedPrivilege(m Mode, id semantics.Ident, p Privilege) {
+func (pd *ProductDTAP) addWarehouse(m Mode, id semantics.Ident) {
 	switch m {
 	case ModeRead:
-		pd.ReadWarehouses[id] = setFlagPrivilegeWarehouse(pd.ReadWarehouses[id], p)
+		pd.ReadWarehouses[id] = [3]bool{}
 	case ModeWrite:
-		pd.WriteWarehouses[id] = setFlagPrivilegeWarehouse(pd.WriteWarehouses[id], p)
+		pd.WriteWarehouses[id] = [3]bool{}
 	}
+	// The operate role can operate and monitor all warehouses of the product dtap
+	pd.OperateWarehouses[id] = [3]bool{}
+}
+
+func (pd *ProductDTAP) hasWarehouse(m Mode, id semantics.Ident) bool {
+	_, ok := pd.getWarehouses(m)[id]
+	return ok
+}
+
+func (pd *ProductDTAP) setWarehouseGrantedPrivilege(m Mode, id semantics.Ident, p Privilege) {
+	warehouses := pd.getWarehouses(m)
+	warehouses[id] = setFlagPrivilegeWarehouse(warehouses[id], p)
 }
 
 func (pd *ProductDTAP) setWarehouseGrants(ctx context.Context, cnf *Config, conn *sql.DB, productRoles map[ProductRole]struct{}) error {
-	for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
+	for _, pr := range [3]ProductRole{pd.ReadRole, pd.WriteRole, pd.OperateRole} {
 		if _, ok := productRoles[pr]; !ok && cnf.DryRun {
 			continue
 		}
-		for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pr.ID, map[GrantTemplate]struct{}{
-			GrantTemplate{
-				PrivilegeComplete: PrivilegeComplete{Privilege: PrvUsage},
+		match := map[GrantTemplate]struct{}{}
+		for _, p := range getPrivilegesWarehouse(pr.Mode) {
+			match[GrantTemplate{
+				PrivilegeComplete: PrivilegeComplete{Privilege: p},
 				GrantedOn:         ObjTpWarehouse,
-			}: {},
-			GrantTemplate{
-				PrivilegeComplete: PrivilegeComplete{Privilege: PrvOperate},
-				GrantedOn:         ObjTpWarehouse,
-			}: {},
-		}, nil) {
+			}] = struct{}{}
+		}
+		for g, err := range QueryGrantsToRoleFiltered(ctx, cnf, conn, pr.ID, match, nil) {
 			if err != nil {
 				return err
 			}
@@ -78,18 +87,13 @@ func (pd *ProductDTAP) setWarehouseGrants(ctx context.Context, cnf *Config, conn
 
 func (pd *ProductDTAP) getToDoWarehouseGrants() iter.Seq[Grant] {
 	return func(yield func(Grant) bool) {
-		for _, pr := range [2]ProductRole{pd.ReadRole, pd.WriteRole} {
-			m := pd.ReadWarehouses
-			if pr.Mode == ModeWrite {
-				m = pd.WriteWarehouses
-			}
-			for w, flags := range m {
+		for _, pr := range [3]ProductRole{pd.ReadRole, pd.WriteRole, pd.OperateRole} {
+			for w, flags := range pd.getWarehouses(pr.Mode) {
 				prvs := []PrivilegeComplete{}
-				if !hasFlagPrivilegeWarehouse(flags, PrvUsage) {
-					prvs = append(prvs, PrivilegeComplete{Privilege: PrvUsage})
-				}
-				if !hasFlagPrivilegeWarehouse(flags, PrvOperate) {
-					prvs = append(prvs, PrivilegeComplete{Privilege: PrvOperate})
+				for _, p := range getPrivilegesWarehouse(pr.Mode) {
+					if !hasFlagPrivilegeWarehouse(flags, p) {
+						prvs = append(prvs, PrivilegeComplete{Privilege: p})
+					}
 				}
 				if len(prvs) > 0 {
 					if !yield(Grant{
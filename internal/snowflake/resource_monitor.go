@@ -0,0 +1,218 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+type ResourceMonitorDecoded struct {
+	ProductID          string `yaml:"product_id"`
+	DTAP               string `yaml:"dtap,omitempty"` // if empty, the quota holds for all dtaps of the product together
+	CreditQuota        int    `yaml:"credit_quota"`
+	Frequency          string `yaml:"frequency,omitempty"`
+	NotifyAt           []int  `yaml:"notify_at,omitempty"`
+	SuspendAt          *int   `yaml:"suspend_at,omitempty"`
+	SuspendImmediateAt *int   `yaml:"suspend_immediate_at,omitempty"`
+}
+
+// A ResourceMonitor is created by grupr for a product, or a product dtap, and attached to their write warehouses
+type ResourceMonitor struct {
+	Name               semantics.Ident
+	ProductID          string
+	DTAP               string
+	CreditQuota        int
+	Frequency          string
+	NotifyAt           []int
+	SuspendAt          *int
+	SuspendImmediateAt *int
+	Warehouses         map[semantics.Ident]struct{}
+}
+
+// resourceMonitorState is a resource monitor as found in Snowflake
+type resourceMonitorState struct {
+	Name                 semantics.Ident
+	Owner                semantics.Ident
+	CreditQuota          *float64
+	UsedCredits          *float64
+	RemainingCredits     *float64
+	Frequency            string
+	NotifyAt             string
+	SuspendAt            string
+	SuspendImmediatelyAt string
+}
+
+func newResourceMonitorName(semCnf *semantics.Config, productID string, dtap string) semantics.Ident {
+	name := semCnf.Prefix + semantics.NewIdentUnquoted(productID) + semCnf.Infix
+	if dtap != "" {
+		name += semantics.NewIdentUnquoted(dtap) + semCnf.Infix
+	}
+	return name + semantics.Ident("RM")
+}
+
+func newResourceMonitor(semCnf *semantics.Config, rm ResourceMonitorDecoded) (*ResourceMonitor, error) {
+	r := &ResourceMonitor{
+		Name:               newResourceMonitorName(semCnf, rm.ProductID, rm.DTAP),
+		ProductID:          rm.ProductID,
+		DTAP:               rm.DTAP,
+		CreditQuota:        rm.CreditQuota,
+		Frequency:          strings.ToUpper(rm.Frequency),
+		NotifyAt:           slices.Sorted(slices.Values(rm.NotifyAt)),
+		SuspendAt:          rm.SuspendAt,
+		SuspendImmediateAt: rm.SuspendImmediateAt,
+		Warehouses:         map[semantics.Ident]struct{}{},
+	}
+	if r.CreditQuota <= 0 {
+		return r, fmt.Errorf("credit_quota should be positive")
+	}
+	if r.Frequency == "" {
+		r.Frequency = "MONTHLY"
+	}
+	if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY", "NEVER"}, r.Frequency) {
+		return r, fmt.Errorf("invalid frequency '%s'", rm.Frequency)
+	}
+	for _, p := range r.NotifyAt {
+		if p <= 0 {
+			return r, fmt.Errorf("notify_at: percentages should be positive")
+		}
+	}
+	if r.SuspendAt != nil && *r.SuspendAt <= 0 || r.SuspendImmediateAt != nil && *r.SuspendImmediateAt <= 0 {
+		return r, fmt.Errorf("suspend_at and suspend_immediate_at should be positive")
+	}
+	return r, nil
+}
+
+func fmtPercentages(l ...int) string {
+	s := []string{}
+	for _, p := range l {
+		s = append(s, fmt.Sprintf("%d%%", p))
+	}
+	return strings.Join(s, ",")
+}
+
+func (r *ResourceMonitor) buildSQLTriggers() string {
+	l := []string{}
+	for _, p := range r.NotifyAt {
+		l = append(l, fmt.Sprintf("ON %d PERCENT DO NOTIFY", p))
+	}
+	if r.SuspendAt != nil {
+		l = append(l, fmt.Sprintf("ON %d PERCENT DO SUSPEND", *r.SuspendAt))
+	}
+	if r.SuspendImmediateAt != nil {
+		l = append(l, fmt.Sprintf("ON %d PERCENT DO SUSPEND_IMMEDIATE", *r.SuspendImmediateAt))
+	}
+	if len(l) == 0 {
+		return ""
+	}
+	return " TRIGGERS " + strings.Join(l, " ")
+}
+
+func (r *ResourceMonitor) hasTriggers(s resourceMonitorState) bool {
+	var suspendAt, suspendImmediateAt string
+	if r.SuspendAt != nil {
+		suspendAt = fmtPercentages(*r.SuspendAt)
+	}
+	if r.SuspendImmediateAt != nil {
+		suspendImmediateAt = fmtPercentages(*r.SuspendImmediateAt)
+	}
+	return fmtPercentages(r.NotifyAt...) == s.NotifyAt && suspendAt == s.SuspendAt && suspendImmediateAt == s.SuspendImmediatelyAt
+}
+
+func (r *ResourceMonitor) create(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE RESOURCE MONITOR IF NOT EXISTS IDENTIFIER($$%s$$) WITH CREDIT_QUOTA = %d FREQUENCY = %s START_TIMESTAMP = IMMEDIATELY%s`,
+		r.Name, r.CreditQuota, r.Frequency, r.buildSQLTriggers()))
+}
+
+func (r *ResourceMonitor) alter(ctx context.Context, cnf *Config, conn *sql.DB, s resourceMonitorState) error {
+	props := []string{}
+	if s.CreditQuota == nil || int(*s.CreditQuota) != r.CreditQuota {
+		props = append(props, fmt.Sprintf("CREDIT_QUOTA = %d", r.CreditQuota))
+	}
+	if s.Frequency != r.Frequency {
+		// Changing the frequency starts a new interval
+		props = append(props, fmt.Sprintf("FREQUENCY = %s START_TIMESTAMP = IMMEDIATELY", r.Frequency))
+	}
+	var triggers string
+	if !r.hasTriggers(s) {
+		if triggers = r.buildSQLTriggers(); triggers == "" {
+			log.Printf("WARN: resource monitor '%v' has triggers that are no longer declared; triggers can not be removed without re-creating the monitor", r.Name)
+		}
+	}
+	if len(props) == 0 && triggers == "" {
+		return nil
+	}
+	var setClause string
+	if len(props) > 0 {
+		setClause = " SET " + strings.Join(props, " ")
+	}
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER RESOURCE MONITOR IDENTIFIER($$%s$$)%s%s`, r.Name, setClause, triggers))
+}
+
+func DropResourceMonitor(ctx context.Context, cnf *Config, conn *sql.DB, name semantics.Ident) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`DROP RESOURCE MONITOR IF EXISTS IDENTIFIER($$%s$$)`, name))
+}
+
+func SetWarehouseResourceMonitor(ctx context.Context, cnf *Config, conn *sql.DB, warehouse semantics.Ident, rm semantics.Ident) error {
+	if rm == "" {
+		return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER WAREHOUSE IDENTIFIER($$%s$$) UNSET RESOURCE_MONITOR`, warehouse))
+	}
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER WAREHOUSE IDENTIFIER($$%s$$) SET RESOURCE_MONITOR = %s`, warehouse, rm))
+}
+
+func parseCredits(s *string) *float64 {
+	// SHOW RESOURCE MONITORS returns credits as strings
+	if s == nil {
+		return nil
+	}
+	if v, err := strconv.ParseFloat(*s, 64); err == nil {
+		return &v
+	}
+	return nil
+}
+
+func queryResourceMonitors(ctx context.Context, conn *sql.DB) iter.Seq2[resourceMonitorState, error] {
+	return func(yield func(resourceMonitorState, error) bool) {
+		rows, err := conn.QueryContext(ctx, `SHOW RESOURCE MONITORS ->> SELECT
+    "name"
+  , "owner"
+  , "credit_quota"
+  , "used_credits"
+  , "remaining_credits"
+  , "frequency"
+  , COALESCE("notify_at", '')
+  , COALESCE("suspend_at", '')
+  , COALESCE("suspend_immediately_at", '')
+FROM $1`)
+		if err != nil {
+			yield(resourceMonitorState{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s resourceMonitorState
+			var creditQuota, usedCredits, remainingCredits *string
+			if err = rows.Scan(&s.Name, &s.Owner, &creditQuota, &usedCredits, &remainingCredits, &s.Frequency,
+				&s.NotifyAt, &s.SuspendAt, &s.SuspendImmediatelyAt); err != nil {
+				yield(resourceMonitorState{}, fmt.Errorf("queryResourceMonitors: error scanning row: %w", err))
+				return
+			}
+			s.CreditQuota = parseCredits(creditQuota)
+			s.UsedCredits = parseCredits(usedCredits)
+			s.RemainingCredits = parseCredits(remainingCredits)
+			s.Frequency = strings.ToUpper(s.Frequency)
+			if !yield(s, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(resourceMonitorState{}, fmt.Errorf("queryResourceMonitors: error after looping over results: %w", err))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestParseCredits(t *testing.T) {
+	s := func(s string) *string { return &s }
+	f := func(f float64) *float64 { return &f }
+	tests := []struct {
+		s    *string
+		want *float64
+	}{
+		{s: nil, want: nil},
+		{s: s("100.00"), want: f(100)},
+		{s: s("12.5"), want: f(12.5)},
+		{s: s("0"), want: f(0)},
+		{s: s(""), want: nil},
+		{s: s("null"), want: nil},
+	}
+	for i, test := range tests {
+		got := parseCredits(test.s)
+		if (got == nil) != (test.want == nil) {
+			t.Errorf("test %d: parseCredits() = %v, want %v", i, got, test.want)
+		} else if got != nil && *got != *test.want {
+			t.Errorf("test %d: parseCredits() = %v, want %v", i, *got, *test.want)
+		}
+	}
+}
+
+func TestNewResourceMonitor(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("semantics.GetConfig(): %v", err)
+	}
+	pct := func(p int) *int { return &p }
+	tests := []struct {
+		rm        ResourceMonitorDecoded
+		name      semantics.Ident
+		frequency string
+		triggers  string
+		wantErr   bool
+	}{
+		{
+			rm:        ResourceMonitorDecoded{ProductID: "crm", CreditQuota: 100},
+			name:      "_X_CRM_X_RM",
+			frequency: "MONTHLY",
+		},
+		{
+			rm:        ResourceMonitorDecoded{ProductID: "crm", DTAP: "dev", CreditQuota: 10, Frequency: "weekly", NotifyAt: []int{90, 75}, SuspendAt: pct(100), SuspendImmediateAt: pct(110)},
+			name:      "_X_CRM_X_DEV_X_RM",
+			frequency: "WEEKLY",
+			triggers:  " TRIGGERS ON 75 PERCENT DO NOTIFY ON 90 PERCENT DO NOTIFY ON 100 PERCENT DO SUSPEND ON 110 PERCENT DO SUSPEND_IMMEDIATE",
+		},
+		{rm: ResourceMonitorDecoded{ProductID: "crm"}, wantErr: true},
+		{rm: ResourceMonitorDecoded{ProductID: "crm", CreditQuota: 10, Frequency: "hourly"}, wantErr: true},
+		{rm: ResourceMonitorDecoded{ProductID: "crm", CreditQuota: 10, NotifyAt: []int{0}}, wantErr: true},
+		{rm: ResourceMonitorDecoded{ProductID: "crm", CreditQuota: 10, SuspendAt: pct(-1)}, wantErr: true},
+	}
+	for i, test := range tests {
+		r, err := newResourceMonitor(semCnf, test.rm)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("test %d: newResourceMonitor(%v): expected an error", i, test.rm)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: newResourceMonitor(%v): %v", i, test.rm, err)
+			continue
+		}
+		if r.Name != test.name {
+			t.Errorf("test %d: name = %v, want %v", i, r.Name, test.name)
+		}
+		if r.Frequency != test.frequency {
+			t.Errorf("test %d: frequency = %v, want %v", i, r.Frequency, test.frequency)
+		}
+		if got := r.buildSQLTriggers(); got != test.triggers {
+			t.Errorf("test %d: buildSQLTriggers() = %q, want %q", i, got, test.triggers)
+		}
+	}
+}
+
+func TestResourceMonitorHasTriggers(t *testing.T) {
+	pct := func(p int) *int { return &p }
+	r := &ResourceMonitor{NotifyAt: []int{75, 90}, SuspendAt: pct(100)}
+	tests := []struct {
+		s    resourceMonitorState
+		want bool
+	}{
+		{s: resourceMonitorState{NotifyAt: "75%,90%", SuspendAt: "100%"}, want: true},
+		{s: resourceMonitorState{NotifyAt: "75%", SuspendAt: "100%"}, want: false},
+		{s: resourceMonitorState{NotifyAt: "75%,90%"}, want: false},
+		{s: resourceMonitorState{NotifyAt: "75%,90%", SuspendAt: "100%", SuspendImmediatelyAt: "110%"}, want: false},
+	}
+	for i, test := range tests {
+		if got := r.hasTriggers(test.s); got != test.want {
+			t.Errorf("test %d: hasTriggers(%v) = %v, want %v", i, test.s, got, test.want)
+		}
+	}
+}
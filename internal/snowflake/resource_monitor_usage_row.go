@@ -0,0 +1,119 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+type ResourceMonitorUsageRow struct {
+	ProductID        string
+	DTAP             string // empty if the quota holds for the product as a whole
+	ResourceMonitor  string
+	CreditQuota      float64
+	UsedCredits      float64
+	RemainingCredits float64
+}
+
+func (g *Grupin) GetResourceMonitorUsageRows(ctx context.Context, conn *sql.DB) iter.Seq2[ResourceMonitorUsageRow, error] {
+	return func(yield func(ResourceMonitorUsageRow, error) bool) {
+		if len(g.resourceMonitors) == 0 {
+			return
+		}
+		for s, err := range queryResourceMonitors(ctx, conn) {
+			if err != nil {
+				yield(ResourceMonitorUsageRow{}, err)
+				return
+			}
+			rm, ok := g.resourceMonitors[s.Name]
+			if !ok {
+				continue
+			}
+			r := ResourceMonitorUsageRow{ProductID: rm.ProductID, DTAP: rm.DTAP, ResourceMonitor: string(rm.Name)}
+			if s.CreditQuota != nil {
+				r.CreditQuota = *s.CreditQuota
+			}
+			if s.UsedCredits != nil {
+				r.UsedCredits = *s.UsedCredits
+			}
+			if s.RemainingCredits != nil {
+				r.RemainingCredits = *s.RemainingCredits
+			}
+			if !yield(r, nil) {
+				return
+			}
+		}
+	}
+}
+
+// StoreResourceMonitorUsageRows logs the usage, and appends it to the resource_monitor_usage table in the schema
+// configured for grupr, so that the history of credit usage is kept
+func StoreResourceMonitorUsageRows(ctx context.Context, cnf *Config, conn *sql.DB, rows iter.Seq2[ResourceMonitorUsageRow, error]) error {
+	var productIDs []string
+	var dtaps []string
+	var resourceMonitors []string
+	var creditQuotas []float64
+	var usedCredits []float64
+	var remainingCredits []float64
+
+	for r, err := range rows {
+		if err != nil {
+			return err
+		}
+		log.Printf("Product '%s', dtap '%s': used %.2f of %.2f credits", r.ProductID, r.DTAP, r.UsedCredits, r.CreditQuota)
+		productIDs = append(productIDs, r.ProductID)
+		dtaps = append(dtaps, r.DTAP)
+		resourceMonitors = append(resourceMonitors, r.ResourceMonitor)
+		creditQuotas = append(creditQuotas, r.CreditQuota)
+		usedCredits = append(usedCredits, r.UsedCredits)
+		remainingCredits = append(remainingCredits, r.RemainingCredits)
+	}
+
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	sql := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v.%v.resource_monitor_usage (
+	product_id varchar,
+	dtap varchar,
+	resource_monitor varchar,
+	credit_quota number(38, 2),
+	used_credits number(38, 2),
+	remaining_credits number(38, 2),
+	measured_at timestamp_ltz
+)
+`,
+		cnf.Database, cnf.Schema)
+	if err := runSQL(ctx, cnf, conn, sql); err != nil {
+		return fmt.Errorf("create table: %v", err)
+	}
+
+	sql = fmt.Sprintf(`
+INSERT INTO %v.%v.resource_monitor_usage (
+	product_id,
+	dtap,
+	resource_monitor,
+	credit_quota,
+	used_credits,
+	remaining_credits,
+	measured_at
+)
+VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP())
+`,
+		cnf.Database, cnf.Schema)
+	if err := runSQL(ctx, cnf, conn, sql,
+		gosnowflake.Array(productIDs),
+		gosnowflake.Array(dtaps),
+		gosnowflake.Array(resourceMonitors),
+		gosnowflake.Array(creditQuotas),
+		gosnowflake.Array(usedCredits),
+		gosnowflake.Array(remainingCredits)); err != nil {
+		return fmt.Errorf("insert usage: %v", err)
+	}
+	return nil
+}
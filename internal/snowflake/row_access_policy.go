@@ -0,0 +1,152 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// Row access policies are created in the schema configured for grupr, like masking policies. A policy belongs to
+// a single product dtap, and is specific for the user groups that each consuming product dtap is allowed to see.
+// As with masking policies, the name ends with a hash of the body.
+type RowAccessPolicy struct {
+	ProductID string
+	DTAP      string
+	Database  semantics.Ident
+	Schema    semantics.Ident
+	Name      semantics.Ident
+
+	body string // only known for policies computed from the YAML, not for policies queried from Snowflake
+}
+
+func newRowAccessPolicy(semCnf *semantics.Config, cnf *Config, pdID semantics.ProductDTAPID,
+	unrestricted map[semantics.ProductDTAPID]struct{}, restricted map[semantics.ProductDTAPID]map[string]struct{}) RowAccessPolicy {
+	p := RowAccessPolicy{
+		ProductID: pdID.ProductID,
+		DTAP:      pdID.DTAP,
+		Database:  cnf.Database,
+		Schema:    cnf.Schema,
+	}
+	var b strings.Builder
+	b.WriteString("CASE\n  WHEN ")
+	b.WriteString(isProductDTAPInSession(semCnf, pdID))
+	b.WriteString(" THEN TRUE\n")
+	// A consumer of an interface without restrictions on user groups sees all rows, even if it also consumes
+	// an interface with restrictions that contains the same object.
+	for _, consumer := range slices.SortedFunc(maps.Keys(unrestricted), compareProductDTAPIDs) {
+		b.WriteString("  WHEN ")
+		b.WriteString(isProductDTAPInSession(semCnf, consumer))
+		b.WriteString(" THEN TRUE\n")
+	}
+	for _, consumer := range slices.SortedFunc(maps.Keys(restricted), compareProductDTAPIDs) {
+		if _, ok := unrestricted[consumer]; ok {
+			continue
+		}
+		values := []string{}
+		for _, v := range slices.Sorted(maps.Keys(restricted[consumer])) {
+			values = append(values, "$$"+v+"$$")
+		}
+		b.WriteString("  WHEN ")
+		b.WriteString(isProductDTAPInSession(semCnf, consumer))
+		b.WriteString(" THEN user_group IN (")
+		b.WriteString(strings.Join(values, ", "))
+		b.WriteString(")\n")
+	}
+	b.WriteString("  ELSE TRUE\nEND")
+	p.body = b.String()
+	h := fnv.New32a()
+	h.Write([]byte(p.body))
+	p.Name = semCnf.Prefix + semantics.NewIdentUnquoted(p.ProductID) + semCnf.Infix + semantics.NewIdentUnquoted(p.DTAP) + semCnf.Infix +
+		semantics.NewIdentUnquoted("ROWS") + semCnf.Infix + semantics.NewIdentUnquoted(fmt.Sprintf("%08x", h.Sum32()))
+	return p
+}
+
+func newRowAccessPolicyFromIdent(semCnf *semantics.Config, cnf *Config, name semantics.Ident) (RowAccessPolicy, error) {
+	p := RowAccessPolicy{Database: cnf.Database, Schema: cnf.Schema, Name: name}
+	s := string(name)
+	if !strings.HasPrefix(s, string(semCnf.Prefix)) {
+		return p, fmt.Errorf("row access policy does not start with Grupr prefix: '%s'", p)
+	}
+	s = strings.TrimPrefix(s, string(semCnf.Prefix))
+	parts := strings.Split(s, string(semCnf.Infix))
+	if len(parts) != 4 {
+		return p, fmt.Errorf("row access policy does not have four parts: '%s'", p)
+	}
+	if parts[2] != "ROWS" {
+		return p, fmt.Errorf("unimplemented kind '%s' for row access policy '%s'", parts[2], p)
+	}
+	p.ProductID = strings.ToLower(parts[0])
+	p.DTAP = strings.ToLower(parts[1])
+	return p, nil
+}
+
+func QueryRowAccessPolicies(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB) iter.Seq2[RowAccessPolicy, error] {
+	return func(yield func(RowAccessPolicy, error) bool) {
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW ROW ACCESS POLICIES IN SCHEMA IDENTIFIER($$%s.%s$$)
+	->> SELECT "name" FROM $1 WHERE "owner" = '%s'`, cnf.Database, cnf.Schema, string(cnf.Role)))
+		if err != nil {
+			yield(RowAccessPolicy{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name semantics.Ident
+			if err = rows.Scan(&name); err != nil {
+				yield(RowAccessPolicy{}, err)
+				return
+			}
+			if p, err := newRowAccessPolicyFromIdent(semCnf, cnf, name); err != nil {
+				yield(RowAccessPolicy{}, err)
+				return
+			} else if !yield(p, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(RowAccessPolicy{}, err)
+		}
+	}
+}
+
+func (p RowAccessPolicy) ProductDTAPID() semantics.ProductDTAPID {
+	return semantics.ProductDTAPID{ProductID: p.ProductID, DTAP: p.DTAP}
+}
+
+func (p RowAccessPolicy) Create(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE ROW ACCESS POLICY IF NOT EXISTS %s AS (user_group VARCHAR) RETURNS BOOLEAN ->
+%s
+COMMENT = 'Managed by Grupr'`, p, p.body))
+}
+
+func (p RowAccessPolicy) QueryRefs(ctx context.Context, conn *sql.DB) iter.Seq2[PolicyRef, error] {
+	return QueryPolicyRefs(ctx, conn, ObjTpRowAccessPolicy, p.Database, p.Schema, p.Name)
+}
+
+func (p RowAccessPolicy) Drop(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	refs := []PolicyRef{}
+	for r, err := range p.QueryRefs(ctx, conn) {
+		if err != nil {
+			return err
+		}
+		refs = append(refs, r)
+	}
+	if err := DoPolicyRefsSkipErrors(ctx, cnf, conn, slices.Values(refs), true); err != nil {
+		return err
+	}
+	if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`DROP ROW ACCESS POLICY IF EXISTS %s`, p)); err != nil {
+		log.Printf("row access policy %v could not be dropped: %v\n", p, err)
+	}
+	return nil
+}
+
+func (p RowAccessPolicy) String() string {
+	return fmt.Sprintf("%v.%v.%v", p.Database, p.Schema, p.Name)
+}
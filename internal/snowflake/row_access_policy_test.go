@@ -0,0 +1,85 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestNewRowAccessPolicy(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR"}
+	pdID := semantics.ProductDTAPID{ProductID: "crm", DTAP: "p"}
+	a := semantics.ProductDTAPID{ProductID: "a", DTAP: "p"}
+	b := semantics.ProductDTAPID{ProductID: "b", DTAP: "p"}
+	tests := []struct {
+		unrestricted map[semantics.ProductDTAPID]struct{}
+		restricted   map[semantics.ProductDTAPID]map[string]struct{}
+		want         string
+	}{
+		{
+			restricted: map[semantics.ProductDTAPID]map[string]struct{}{a: {"fr": {}, "de": {}}},
+			want: `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN TRUE
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN user_group IN ($$de$$, $$fr$$)
+  ELSE TRUE
+END`,
+		},
+		{
+			// b also consumes an interface without restrictions on user groups, so it sees all rows
+			unrestricted: map[semantics.ProductDTAPID]struct{}{b: {}},
+			restricted:   map[semantics.ProductDTAPID]map[string]struct{}{a: {"fr": {}}, b: {"de": {}}},
+			want: `CASE
+  WHEN IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_CRM_X_P_X_W$$) THEN TRUE
+  WHEN IS_ROLE_IN_SESSION($$_X_B_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_B_X_P_X_W$$) THEN TRUE
+  WHEN IS_ROLE_IN_SESSION($$_X_A_X_P_X_R$$) OR IS_ROLE_IN_SESSION($$_X_A_X_P_X_W$$) THEN user_group IN ($$fr$$)
+  ELSE TRUE
+END`,
+		},
+	}
+	names := map[semantics.Ident]struct{}{}
+	for i, test := range tests {
+		p := newRowAccessPolicy(semCnf, cnf, pdID, test.unrestricted, test.restricted)
+		if p.body != test.want {
+			t.Errorf("test %d: body = %q, want %q", i, p.body, test.want)
+		}
+		if _, ok := names[p.Name]; ok {
+			t.Errorf("test %d: name %v is not unique", i, p.Name)
+		}
+		names[p.Name] = struct{}{}
+		q, err := newRowAccessPolicyFromIdent(semCnf, cnf, p.Name)
+		if err != nil {
+			t.Errorf("test %d: newRowAccessPolicyFromIdent(%v): %v", i, p.Name, err)
+		} else if q.ProductDTAPID() != pdID || q.String() != p.String() {
+			t.Errorf("test %d: newRowAccessPolicyFromIdent(%v) = %v, want %v", i, p.Name, q, p)
+		}
+	}
+}
+
+func TestNewRowAccessPolicyFromIdent(t *testing.T) {
+	semCnf, err := semantics.GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cnf := &Config{Database: "GRUPR_DB", Schema: "GRUPR"}
+	tests := []struct {
+		name    semantics.Ident
+		wantErr bool
+	}{
+		{name: "_X_CRM_X_P_X_ROWS_X_0123abcd"},
+		{name: "CRM_X_P_X_ROWS_X_0123abcd", wantErr: true},
+		{name: "_X_CRM_X_P_X_ROWS", wantErr: true},
+		{name: "_X_CRM_X_P_X_COLS_X_0123abcd", wantErr: true},
+	}
+	for _, test := range tests {
+		_, err := newRowAccessPolicyFromIdent(semCnf, cnf, test.name)
+		if test.wantErr && err == nil {
+			t.Errorf("newRowAccessPolicyFromIdent(%v): expected an error", test.name)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("newRowAccessPolicyFromIdent(%v): %v", test.name, err)
+		}
+	}
+}
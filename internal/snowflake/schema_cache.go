@@ -9,12 +9,13 @@ import (
 )
 
 type schemaCache struct {
-	mu      sync.Mutex // guards objects and version
-	version int
-	objects map[semantics.Ident]ObjAttr // nil: never requested; empty: none present;
+	mu         sync.Mutex // guards objects, hasColumns, and version
+	version    int
+	objects    map[semantics.Ident]ObjAttr // nil: never requested; empty: none present;
+	hasColumns bool                        // once columns have been requested, we keep loading them when refreshing
 }
 
-func (c *schemaCache) refreshObjects(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) error {
+func (c *schemaCache) refreshObjects(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident, withColumns bool) error {
 	// intended to be called from accountCache.match and friends, which will acquire locks on the appropriate mutexes
 	c.objects = map[semantics.Ident]ObjAttr{} // overwrite if it had a value
 	for obj, err := range QueryObjs(ctx, conn, db, schema) {
@@ -23,6 +24,43 @@ func (c *schemaCache) refreshObjects(ctx context.Context, conn *sql.DB, db seman
 		}
 		c.objects[obj.Name] = ObjAttr{ObjectType: obj.ObjectType, Owner: obj.Owner}
 	}
+	for obj, err := range QueryFunctionsProcedures(ctx, conn, db, schema) {
+		if err != nil {
+			return err
+		}
+		c.objects[obj.Name] = ObjAttr{ObjectType: obj.ObjectType, Owner: obj.Owner}
+	}
+	for obj, err := range QuerySequencesPipesTasksStreams(ctx, conn, db, schema) {
+		if err != nil {
+			return err
+		}
+		c.objects[obj.Name] = ObjAttr{ObjectType: obj.ObjectType, Owner: obj.Owner}
+	}
+	if withColumns || c.hasColumns {
+		if err := c.refreshColumns(ctx, conn, db, schema); err != nil {
+			return err
+		}
+	}
 	c.version += 1
 	return nil
 }
+
+func (c *schemaCache) refreshColumns(ctx context.Context, conn *sql.DB, db semantics.Ident, schema semantics.Ident) error {
+	// Objects may have been created or dropped in between querying objects and columns; we only keep
+	// columns of objects we know about. Objects without columns get an empty, non-nil, slice.
+	for k, v := range c.objects {
+		v.Columns = []Column{}
+		c.objects[k] = v
+	}
+	for col, err := range QueryColumns(ctx, conn, db, schema) {
+		if err != nil {
+			return err
+		}
+		if v, ok := c.objects[col.Object]; ok {
+			v.Columns = append(v.Columns, col)
+			c.objects[col.Object] = v
+		}
+	}
+	c.hasColumns = true
+	return nil
+}
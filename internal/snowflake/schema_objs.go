@@ -13,7 +13,7 @@ func newSchemaObjs(db semantics.Ident, schema semantics.Ident, o SchemaObjs, om
 	r := SchemaObjs{Objects: map[semantics.Ident]ObjAttr{}}
 	r = r.setMatchAllObjects(db, schema, om)
 	for k, v := range o.Objects {
-		if !om.DisjointFromObject(db, schema, k) {
+		if !om.DisjointFromObject(db, schema, objName(v.ObjectType, k)) {
 			r.Objects[k] = v
 		}
 	}
@@ -0,0 +1,241 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+type SessionPolicyDecoded struct {
+	Name                  string   `yaml:"name"`
+	AllowedSecondaryRoles []string `yaml:"allowed_secondary_roles"`
+	Users                 []string `yaml:"users"`
+}
+
+// Product roles rely on being the only role that is active in a session, see the README. If grupr manages session
+// policies, it attaches a session policy that does not allow secondary roles to the account, and one that allows all
+// of them to the grupr user. Users that need to combine product roles with other roles, that have been approved for
+// this, get a session policy that allows just those roles. Session policies are created in the schema configured for
+// grupr (GRUPR_SNOWFLAKE_DB, GRUPR_SNOWFLAKE_SCHEMA).
+type SessionPolicy struct {
+	Name                  semantics.Ident
+	AllowedSecondaryRoles []string // sorted; ALL means all roles
+	Users                 map[semantics.Ident]struct{}
+}
+
+const (
+	sessionPolicyDefault = "DEFAULT"
+	sessionPolicyGrupr   = "GRUPR"
+)
+
+func newSessionPolicyName(semCnf *semantics.Config, name string) semantics.Ident {
+	return semCnf.Prefix + semantics.Ident("SP") + semCnf.Infix + semantics.NewIdentUnquoted(name)
+}
+
+func newSessionPolicy(semCnf *semantics.Config, p SessionPolicyDecoded) (*SessionPolicy, error) {
+	r := &SessionPolicy{
+		Name:  newSessionPolicyName(semCnf, p.Name),
+		Users: map[semantics.Ident]struct{}{},
+	}
+	if _, err := semantics.NewID(semCnf, p.Name); err != nil {
+		return r, err
+	}
+	if n := strings.ToUpper(p.Name); n == sessionPolicyDefault || n == sessionPolicyGrupr {
+		return r, fmt.Errorf("name '%s' is reserved", p.Name)
+	}
+	if len(p.AllowedSecondaryRoles) == 0 {
+		return r, fmt.Errorf("allowed_secondary_roles: specify at least one role")
+	}
+	for _, s := range p.AllowedSecondaryRoles {
+		role, err := semantics.NewIdentStripQuotesIfAny(s, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr)
+		if err != nil {
+			return r, err
+		}
+		if strings.HasPrefix(string(role), string(semCnf.Prefix)) {
+			return r, fmt.Errorf("allowed_secondary_roles: grupr managed role '%v' can not be combined with other roles", role)
+		}
+		r.AllowedSecondaryRoles = append(r.AllowedSecondaryRoles, string(role))
+	}
+	slices.Sort(r.AllowedSecondaryRoles)
+	for _, s := range p.Users {
+		user, err := semantics.NewIdentStripQuotesIfAny(s, semCnf.ValidQuotedExpr, semCnf.ValidUnquotedExpr)
+		if err != nil {
+			return r, err
+		}
+		if _, ok := r.Users[user]; ok {
+			return r, fmt.Errorf("users: duplicate user '%v'", user)
+		}
+		r.Users[user] = struct{}{}
+	}
+	return r, nil
+}
+
+func (p *SessionPolicy) fqn(cnf *Config) string {
+	return fmt.Sprintf("%s.%s.%s", cnf.Database, cnf.Schema, p.Name)
+}
+
+func (p *SessionPolicy) buildSQLAllowedSecondaryRoles() string {
+	l := []string{}
+	for _, r := range p.AllowedSecondaryRoles {
+		l = append(l, "$$"+r+"$$")
+	}
+	return fmt.Sprintf("ALLOWED_SECONDARY_ROLES = (%s)", strings.Join(l, ", "))
+}
+
+func (p *SessionPolicy) create(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE SESSION POLICY IF NOT EXISTS %s %s`, p.fqn(cnf), p.buildSQLAllowedSecondaryRoles()))
+}
+
+func (p *SessionPolicy) alterIfNeeded(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	var allowed string
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf(`DESCRIBE SESSION POLICY %s ->> SELECT "allowed_secondary_roles" FROM $1`,
+		p.fqn(cnf))).Scan(&allowed); err != nil {
+		return err
+	}
+	// The value is a list like [ROLE_A, ROLE_B], possibly with quotes
+	actual := []string{}
+	for _, s := range strings.Split(strings.Trim(allowed, "[]"), ",") {
+		if s = strings.Trim(strings.TrimSpace(s), `"'`); s != "" {
+			actual = append(actual, s)
+		}
+	}
+	slices.Sort(actual)
+	if slices.Equal(actual, p.AllowedSecondaryRoles) {
+		return nil
+	}
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER SESSION POLICY %s SET %s`, p.fqn(cnf), p.buildSQLAllowedSecondaryRoles()))
+}
+
+// querySessionPolicyOf returns the fully qualified name of the session policy attached to the account or user, or the
+// empty string if there is none; domain is ACCOUNT or USER
+func querySessionPolicyOf(ctx context.Context, cnf *Config, conn *sql.DB, domain ObjType, name string) (string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT
+    policy_db || '.' || policy_schema || '.' || policy_name
+FROM TABLE(%s.INFORMATION_SCHEMA.POLICY_REFERENCES(REF_ENTITY_NAME => $$%s$$, REF_ENTITY_DOMAIN => '%v'))
+WHERE policy_kind = 'SESSION_POLICY'`, cnf.Database, name, domain))
+	if err != nil {
+		if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+			err = ErrObjectNotExistOrAuthorized
+		}
+		return "", err
+	}
+	defer rows.Close()
+	var fqn string
+	for rows.Next() {
+		if err = rows.Scan(&fqn); err != nil {
+			return "", fmt.Errorf("querySessionPolicyOf: error scanning row: %w", err)
+		}
+	}
+	return fqn, rows.Err()
+}
+
+func queryCurrentAccount(ctx context.Context, conn *sql.DB) (string, error) {
+	var account string
+	err := conn.QueryRowContext(ctx, `SELECT CURRENT_ACCOUNT()`).Scan(&account)
+	return account, err
+}
+
+// queryUsersOfSessionPolicy returns the users that the session policy is attached to
+func queryUsersOfSessionPolicy(ctx context.Context, cnf *Config, conn *sql.DB, policy semantics.Ident) iter.Seq2[semantics.Ident, error] {
+	return func(yield func(semantics.Ident, error) bool) {
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT ref_entity_name
+FROM TABLE(%s.INFORMATION_SCHEMA.POLICY_REFERENCES(POLICY_NAME => $$%s.%s.%s$$))
+WHERE UPPER(ref_entity_domain) = 'USER'`, cnf.Database, cnf.Database, cnf.Schema, policy))
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var user semantics.Ident
+			if err = rows.Scan(&user); err != nil {
+				yield("", fmt.Errorf("queryUsersOfSessionPolicy: error scanning row: %w", err))
+				return
+			}
+			if !yield(user, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield("", fmt.Errorf("queryUsersOfSessionPolicy: error after looping over results: %w", err))
+		}
+	}
+}
+
+func querySessionPolicies(ctx context.Context, cnf *Config, conn *sql.DB) iter.Seq2[semantics.Ident, error] {
+	return func(yield func(semantics.Ident, error) bool) {
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SHOW SESSION POLICIES IN SCHEMA IDENTIFIER($$%s.%s$$) ->> SELECT "name" FROM $1`,
+			cnf.Database, cnf.Schema))
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name semantics.Ident
+			if err = rows.Scan(&name); err != nil {
+				yield("", fmt.Errorf("querySessionPolicies: error scanning row: %w", err))
+				return
+			}
+			if !yield(name, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield("", fmt.Errorf("querySessionPolicies: error after looping over results: %w", err))
+		}
+	}
+}
+
+// attachSessionPolicy attaches p to the account or user, replacing a session policy managed by grupr; a session
+// policy that is not managed by grupr is left in place
+func attachSessionPolicy(ctx context.Context, semCnf *semantics.Config, cnf *Config, conn *sql.DB, p *SessionPolicy,
+	domain ObjType, name string) error {
+	attached, err := querySessionPolicyOf(ctx, cnf, conn, domain, name)
+	if err != nil {
+		return err
+	}
+	target := "ACCOUNT"
+	if domain == ObjTpUser {
+		target = fmt.Sprintf("USER IDENTIFIER($$%s$$)", name)
+	}
+	if attached != "" {
+		if strings.EqualFold(attached, fmt.Sprintf("%s.%s.%s", string(cnf.Database), string(cnf.Schema), string(p.Name))) {
+			return nil
+		}
+		if !strings.HasPrefix(attached, fmt.Sprintf("%s.%s.%s", string(cnf.Database), string(cnf.Schema), string(semCnf.Prefix))) {
+			log.Printf("WARN: %v '%s' has session policy '%s', which is not managed by grupr, leaving it", domain, name, attached)
+			return nil
+		}
+		if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER %s UNSET SESSION POLICY`, target)); err != nil {
+			return err
+		}
+	}
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER %s SET SESSION POLICY %s`, target, p.fqn(cnf)))
+}
+
+// CheckAccountSessionPolicy warns if no session policy is attached to the account, while grupr is not managing
+// session policies either
+func CheckAccountSessionPolicy(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	if cnf.ManageSessionPolicies {
+		return nil
+	}
+	account, err := queryCurrentAccount(ctx, conn)
+	if err != nil {
+		return err
+	}
+	attached, err := querySessionPolicyOf(ctx, cnf, conn, ObjTpAccount, account)
+	if err != nil {
+		return err
+	}
+	if attached == "" {
+		log.Printf("WARN: no session policy is attached to the account; users can activate secondary roles next to product roles; set GRUPR_SNOWFLAKE_MANAGE_SESSION_POLICIES=true to have grupr manage session policies")
+	}
+	return nil
+}
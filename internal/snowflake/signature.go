@@ -0,0 +1,126 @@
+package snowflake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// Functions and procedures can be overloaded. We identify them by their name followed by the data types of their
+// arguments, e.g., MY_FUNC(NUMBER, VARCHAR), the same way they are identified in DDL statements. Object expressions
+// match the name only, so they match all overloads of a function or procedure.
+
+func hasSignature(ot ObjType) bool {
+	return ot == ObjTpFunction || ot == ObjTpProcedure
+}
+
+// newSignatureIdent parses an argument signature like (A NUMBER, B VARCHAR), as found in information_schema, or
+// (A NUMBER(38,0), B VARCHAR):NUMBER(38,0), as found in the output of SHOW GRANTS, and returns the identifier we
+// use for the function or procedure. Argument names may be quoted, and may then hold any character.
+func newSignatureIdent(name semantics.Ident, argSignature string) (semantics.Ident, error) {
+	s := strings.TrimSpace(argSignature)
+	if !strings.HasPrefix(s, "(") {
+		return "", fmt.Errorf("invalid argument signature: '%s'", argSignature)
+	}
+	types := []string{}
+	depth := 0
+	start := 1
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			inQuotes = !inQuotes // an escaped quote, "", toggles twice
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		switch s[i] {
+		case '(':
+			depth += 1
+		case ')', ',':
+			if s[i] == ')' {
+				depth -= 1
+			}
+			if depth == 0 || depth == 1 && s[i] == ',' {
+				if arg := strings.TrimSpace(s[start:i]); arg != "" {
+					if t, err := argType(arg); err != nil {
+						return "", fmt.Errorf("invalid argument signature: '%s': %w", argSignature, err)
+					} else {
+						types = append(types, t)
+					}
+				}
+				start = i + 1
+			}
+		}
+		if depth == 0 {
+			break // we are not interested in the return type
+		}
+	}
+	if depth != 0 || inQuotes {
+		return "", fmt.Errorf("invalid argument signature: '%s'", argSignature)
+	}
+	return semantics.Ident(string(name) + "(" + strings.Join(types, ", ") + ")"), nil
+}
+
+// argType returns the data type of an argument like A NUMBER(38,0), "my arg" VARCHAR, or NUMBER(38, 0); it drops the
+// argument name, if any, and the precision, length, or element type, if any
+func argType(arg string) (string, error) {
+	if strings.HasPrefix(arg, `"`) {
+		// Skip the quoted name; a quote within it is escaped by doubling it
+		i := 1
+		for ; i < len(arg); i++ {
+			if arg[i] != '"' {
+				continue
+			}
+			if i+1 < len(arg) && arg[i+1] == '"' {
+				i++
+				continue
+			}
+			break
+		}
+		if i >= len(arg) {
+			return "", fmt.Errorf("unterminated quoted identifier in argument '%s'", arg)
+		}
+		arg = strings.TrimSpace(arg[i+1:])
+	} else if i := strings.IndexAny(arg, " \t("); i != -1 && arg[i] != '(' {
+		// The argument has an unquoted name, which is followed by white space, not by a parenthesis
+		arg = strings.TrimSpace(arg[i+1:])
+	}
+	if i := strings.Index(arg, "("); i != -1 {
+		arg = arg[:i]
+	}
+	arg = strings.ToUpper(strings.TrimSpace(arg))
+	if arg == "" {
+		return "", fmt.Errorf("no data type in argument")
+	}
+	return arg, nil
+}
+
+// splitSignatureIdent returns the name and argument types of the identifier of a function or procedure
+func splitSignatureIdent(obj semantics.Ident) (semantics.Ident, string) {
+	s := string(obj)
+	if i := strings.LastIndex(s, "("); i != -1 && strings.HasSuffix(s, ")") {
+		return semantics.Ident(s[:i]), s[i:]
+	}
+	return obj, ""
+}
+
+// objName returns the name of an object, which is what object expressions match
+func objName(ot ObjType, obj semantics.Ident) semantics.Ident {
+	if hasSignature(ot) {
+		name, _ := splitSignatureIdent(obj)
+		return name
+	}
+	return obj
+}
+
+// objSQL returns how an object within a schema is referred to in SQL statements
+func objSQL(ot ObjType, db semantics.Ident, schema semantics.Ident, obj semantics.Ident) string {
+	if hasSignature(ot) {
+		// IDENTIFIER() can not hold argument types
+		name, args := splitSignatureIdent(obj)
+		return fmt.Sprintf(`%s.%s.%s%s`, db, schema, name, args)
+	}
+	return fmt.Sprintf(`IDENTIFIER($$%s.%s.%s$$)`, db, schema, obj)
+}
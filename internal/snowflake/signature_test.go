@@ -0,0 +1,86 @@
+package snowflake
+
+import (
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+func TestNewSignatureIdent(t *testing.T) {
+	tests := []struct {
+		name         semantics.Ident
+		argSignature string
+		want         semantics.Ident
+		wantErr      bool
+	}{
+		{
+			name:         "MY_FUNC",
+			argSignature: "()",
+			want:         "MY_FUNC()",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(A NUMBER, B VARCHAR)",
+			want:         "MY_FUNC(NUMBER, VARCHAR)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(A NUMBER(38,0), B VARCHAR):NUMBER(38,0)",
+			want:         "MY_FUNC(NUMBER, VARCHAR)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(A NUMBER(38, 0), B TIMESTAMP_NTZ(9))",
+			want:         "MY_FUNC(NUMBER, TIMESTAMP_NTZ)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(NUMBER(38, 0), TIMESTAMP_NTZ(9))",
+			want:         "MY_FUNC(NUMBER, TIMESTAMP_NTZ)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: `("my arg" NUMBER, "a, (b)" VARCHAR)`,
+			want:         "MY_FUNC(NUMBER, VARCHAR)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: `("say ""hi""" varchar)`,
+			want:         "MY_FUNC(VARCHAR)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(A ARRAY(NUMBER), B VECTOR(FLOAT, 256))",
+			want:         "MY_FUNC(ARRAY, VECTOR)",
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "A NUMBER",
+			wantErr:      true,
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: "(A NUMBER",
+			wantErr:      true,
+		},
+		{
+			name:         "MY_FUNC",
+			argSignature: `("my arg NUMBER)`,
+			wantErr:      true,
+		},
+	}
+	for _, test := range tests {
+		got, err := newSignatureIdent(test.name, test.argSignature)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("newSignatureIdent(%q, %q): expected an error, got '%v'", string(test.name), test.argSignature, string(got))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newSignatureIdent(%q, %q): %v", string(test.name), test.argSignature, err)
+		} else if got != test.want {
+			t.Errorf("newSignatureIdent(%q, %q) = '%v', want '%v'", string(test.name), test.argSignature, string(got), string(test.want))
+		}
+	}
+}
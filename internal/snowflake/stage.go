@@ -0,0 +1,23 @@
+package snowflake
+
+type StageDecoded struct {
+	Database      string   `yaml:"database"`
+	Schema        string   `yaml:"schema"`
+	Ident         string   `yaml:"ident"`
+	Mode          string   `yaml:"mode"`
+	External      bool     `yaml:"external,omitempty"`
+	SharedBetween []string `yaml:"shared_between,omitempty"`
+	OnlyProd      bool     `yaml:"only_prod,omitempty"`
+	OnlyNonProd   bool     `yaml:"only_non_prod,omitempty"`
+}
+
+func getPrivilegesStage(m Mode, external bool) []Privilege {
+	// External stages only have USAGE; on internal stages, WRITE can only be granted along with READ
+	if external {
+		return []Privilege{PrvUsage}
+	}
+	if m == ModeWrite {
+		return []Privilege{PrvRead, PrvWrite}
+	}
+	return []Privilege{PrvRead}
+}
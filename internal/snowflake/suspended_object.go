@@ -0,0 +1,223 @@
+package snowflake
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Ownership of a pipe can only be transferred while it is paused, and ownership of a task only while it is suspended.
+// Grupr can suspend running pipes and tasks itself, if it is allowed to, either with the --auto-suspend flag, or by
+// confirming at a prompt. Before suspending an object, grupr records it in the suspended_objects table in the schema
+// configured for grupr (GRUPR_SNOWFLAKE_DB, GRUPR_SNOWFLAKE_SCHEMA), and it forgets about the object only after it
+// has resumed it. If grupr crashes midway, the next run resumes the objects it finds in this table.
+type suspendedObject struct {
+	ObjectType ObjType
+	ObjectID
+}
+
+// Product dtaps are processed concurrently; only one of them should prompt at a time
+var promptMu sync.Mutex
+
+func (o suspendedObject) String() string {
+	return fmt.Sprintf("%v %s.%s.%s", o.ObjectType, o.Database, o.Schema, o.Object)
+}
+
+func (o suspendedObject) isRunning(ctx context.Context, conn *sql.DB) (bool, error) {
+	var q string
+	switch o.ObjectType {
+	case ObjTpPipe:
+		q = fmt.Sprintf(`SELECT PARSE_JSON(SYSTEM$PIPE_STATUS($$%s.%s.%s$$)):executionState::varchar = 'RUNNING'`,
+			o.Database, o.Schema, o.Object)
+	case ObjTpTask:
+		q = fmt.Sprintf(`SHOW TASKS LIKE $$%s$$ IN SCHEMA IDENTIFIER($$%s.%s$$) ->> SELECT COUNT_IF("state" = 'started') > 0 FROM $1 WHERE "name" = $$%s$$`,
+			string(o.Object), o.Database, o.Schema, string(o.Object))
+	default:
+		panic("object type can not be suspended")
+	}
+	var running bool
+	if err := conn.QueryRowContext(ctx, q).Scan(&running); err != nil {
+		if strings.Contains(err.Error(), "390201") { // ErrObjectNotExistOrAuthorized; this way of testing error code is used in errors_test in the gosnowflake repo
+			err = ErrObjectNotExistOrAuthorized
+		}
+		return false, err
+	}
+	return running, nil
+}
+
+func (o suspendedObject) buildSQL(resume bool) string {
+	switch o.ObjectType {
+	case ObjTpPipe:
+		if resume {
+			// After a change of ownership, a pipe can only be resumed with SYSTEM$PIPE_FORCE_RESUME
+			return fmt.Sprintf(`SELECT SYSTEM$PIPE_FORCE_RESUME($$%s.%s.%s$$)`, o.Database, o.Schema, o.Object)
+		}
+		return fmt.Sprintf(`ALTER PIPE IDENTIFIER($$%s.%s.%s$$) SET PIPE_EXECUTION_PAUSED = TRUE`, o.Database, o.Schema, o.Object)
+	case ObjTpTask:
+		if resume {
+			return fmt.Sprintf(`ALTER TASK IDENTIFIER($$%s.%s.%s$$) RESUME`, o.Database, o.Schema, o.Object)
+		}
+		return fmt.Sprintf(`ALTER TASK IDENTIFIER($$%s.%s.%s$$) SUSPEND`, o.Database, o.Schema, o.Object)
+	default:
+		panic("object type can not be suspended")
+	}
+}
+
+func (o suspendedObject) suspend(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	// Record the object first, so that we will resume it, even if we crash right after suspending it
+	if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`INSERT INTO %s.%s.suspended_objects (object_type, database_name, schema_name, object_name, suspended_at)
+SELECT ?, ?, ?, ?, CURRENT_TIMESTAMP()`, cnf.Database, cnf.Schema),
+		o.ObjectType.String(), string(o.Database), string(o.Schema), string(o.Object)); err != nil {
+		return fmt.Errorf("record suspended object %v: %w", o, err)
+	}
+	// Make sure we can still resume the object after transferring its ownership; the grant is copied along with
+	// the other outbound grants on the object
+	if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`GRANT OPERATE ON %s %s TO ROLE IDENTIFIER($$%s$$)`,
+		o.ObjectType.sql(), objSQL(o.ObjectType, o.Database, o.Schema, o.Object), cnf.Role)); err != nil {
+		return err
+	}
+	return runSQL(ctx, cnf, conn, o.buildSQL(false))
+}
+
+func (o suspendedObject) resume(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	if err := runSQL(ctx, cnf, conn, o.buildSQL(true)); err != nil {
+		return err
+	}
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`DELETE FROM %s.%s.suspended_objects
+WHERE object_type = ? AND database_name = ? AND schema_name = ? AND object_name = ?`, cnf.Database, cnf.Schema),
+		o.ObjectType.String(), string(o.Database), string(o.Schema), string(o.Object))
+}
+
+func CreateSuspendedObjectsTable(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s.suspended_objects (
+	object_type varchar,
+	database_name varchar,
+	schema_name varchar,
+	object_name varchar,
+	suspended_at timestamp_ltz
+)`, cnf.Database, cnf.Schema))
+}
+
+func querySuspendedObjects(ctx context.Context, cnf *Config, conn *sql.DB) ([]suspendedObject, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT object_type, database_name, schema_name, object_name
+FROM %s.%s.suspended_objects`, cnf.Database, cnf.Schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	objs := []suspendedObject{}
+	for rows.Next() {
+		var objType string
+		var o suspendedObject
+		if err = rows.Scan(&objType, &o.Database, &o.Schema, &o.Object); err != nil {
+			return nil, err
+		}
+		o.ObjectType = ParseObjType(objType)
+		if !o.ObjectType.isSuspendable() {
+			return nil, fmt.Errorf("suspended_objects: unexpected object type '%s'", objType)
+		}
+		objs = append(objs, o)
+	}
+	return objs, rows.Err()
+}
+
+// ResumeSuspendedObjects resumes the pipes and tasks that an earlier run of grupr suspended, but did not resume, e.g.,
+// because it crashed midway.
+func ResumeSuspendedObjects(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	if cnf.DryRun {
+		// In dry run mode, we did not really create the table, so it may not exist yet
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`DESCRIBE TABLE %s.%s.suspended_objects`, cnf.Database, cnf.Schema)); err != nil {
+			return nil
+		}
+	}
+	objs, err := querySuspendedObjects(ctx, cnf, conn)
+	if err != nil {
+		return err
+	}
+	for _, o := range objs {
+		log.Printf("Resuming %v, suspended by an earlier run of grupr", o)
+		if err := o.resume(ctx, cnf, conn); err != nil {
+			if err != ErrObjectNotExistOrAuthorized {
+				return err
+			}
+			log.Printf("WARN: could not resume %v, it was dropped, or grupr is not authorized; please check", o)
+		}
+	}
+	return nil
+}
+
+func confirmSuspend(objs []suspendedObject) bool {
+	// We only prompt if there is someone to answer
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	fmt.Fprintln(os.Stderr, "To transfer their ownership, grupr needs to suspend and then resume these objects:")
+	for _, o := range objs {
+		fmt.Fprintf(os.Stderr, "  %v\n", o)
+	}
+	fmt.Fprint(os.Stderr, "Suspend them now? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// DoOwnershipGrants grants ownership one by one, like DoGrantsIndividually; running pipes and tasks are suspended
+// while their ownership is transferred, if the user allows it; otherwise, their ownership is left as is. done is
+// called after each grant that succeeded.
+func DoOwnershipGrants(ctx context.Context, cnf *Config, conn *sql.DB, grants iter.Seq[Grant], done func(Grant)) error {
+	running := map[suspendedObject]Grant{}
+	runningObjs := []suspendedObject{}
+	for g := range grants {
+		if g.GrantedOn.isSuspendable() {
+			o := suspendedObject{ObjectType: g.GrantedOn, ObjectID: ObjectID{Database: g.Database, Schema: g.Schema, Object: g.Object}}
+			if isRunning, err := o.isRunning(ctx, conn); err != nil {
+				return err
+			} else if isRunning {
+				running[o] = g
+				runningObjs = append(runningObjs, o)
+				continue
+			}
+		}
+		if err := runSQL(ctx, cnf, conn, g.buildSQLGrant(false)); err != nil {
+			return err
+		}
+		done(g)
+	}
+	if len(runningObjs) == 0 {
+		return nil
+	}
+	if !cnf.AutoSuspend && (cnf.DryRun || !confirmSuspend(runningObjs)) {
+		for _, o := range runningObjs {
+			log.Printf("WARN: not transferring ownership of running %v; run grupr with --auto-suspend, or interactively, to suspend it", o)
+		}
+		return nil
+	}
+	for _, o := range runningObjs {
+		if err := o.suspend(ctx, cnf, conn); err != nil {
+			return err
+		}
+		if err := runSQL(ctx, cnf, conn, running[o].buildSQLGrant(false)); err != nil {
+			// Do not leave the object suspended until some later run resumes it
+			if errResume := o.resume(ctx, cnf, conn); errResume != nil {
+				log.Printf("WARN: could not resume %v after failing to transfer its ownership: %v; the next run of grupr will try again", o, errResume)
+			}
+			return err
+		}
+		done(running[o])
+		if err := o.resume(ctx, cnf, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// Object tags are created in the schema configured for grupr (GRUPR_SNOWFLAKE_DB, GRUPR_SNOWFLAKE_SCHEMA), and
+// are set on the databases, schemas, and objects matched by product dtaps, so that governance tooling in
+// Snowflake can work with the metadata in the YAML. Tags are only managed if GRUPR_SNOWFLAKE_MANAGE_TAGS is set.
+type Tag int
+
+const (
+	TagProductID Tag = iota
+	TagDTAP
+	TagClassification
+	TagUserGroups
+	TagInterfaces
+)
+
+var tags = [...]Tag{TagProductID, TagDTAP, TagClassification, TagUserGroups, TagInterfaces}
+
+func (t Tag) String() string {
+	return map[Tag]string{
+		TagProductID:      "PRODUCT_ID",
+		TagDTAP:           "DTAP",
+		TagClassification: "CLASSIFICATION",
+		TagUserGroups:     "USER_GROUPS",
+		TagInterfaces:     "INTERFACES",
+	}[t]
+}
+
+// TagValues are indexed by Tag; an empty string means the YAML has no value for the tag, e.g., an object
+// that is not part of any interface.
+type TagValues [len(tags)]string
+
+// A TagRef is the set of tags on a database (Schema and Object empty), schema (Object empty), or object.
+type TagRef struct {
+	ObjectType ObjType
+	Database   semantics.Ident
+	Schema     semantics.Ident
+	Object     semantics.Ident
+	Values     TagValues // not used when unsetting tags
+}
+
+func (r TagRef) buildSQL(cnf *Config, unset bool) string {
+	var name string
+	switch r.ObjectType {
+	case ObjTpDatabase:
+		name = fmt.Sprintf(`IDENTIFIER($$%s$$)`, r.Database)
+	case ObjTpSchema:
+		name = fmt.Sprintf(`IDENTIFIER($$%s.%s$$)`, r.Database, r.Schema)
+	case ObjTpTable, ObjTpView, ObjTpMaterializedView, ObjTpDynamicTable, ObjTpExternalTable, ObjTpIcebergTable, ObjTpEventTable,
+		ObjTpFunction, ObjTpProcedure, ObjTpSequence, ObjTpPipe, ObjTpTask, ObjTpStream:
+		name = objSQL(r.ObjectType, r.Database, r.Schema, r.Object)
+	default:
+		panic("object type can not be tagged")
+	}
+	l := []string{}
+	for _, t := range tags {
+		if unset {
+			l = append(l, fmt.Sprintf("%s.%s.%v", cnf.Database, cnf.Schema, t))
+		} else {
+			l = append(l, fmt.Sprintf("%s.%s.%v = '%s'", cnf.Database, cnf.Schema, t, r.Values[t]))
+		}
+	}
+	if unset {
+		return fmt.Sprintf(`ALTER %s %s UNSET TAG %s`, r.ObjectType.sql(), name, strings.Join(l, ", "))
+	}
+	return fmt.Sprintf(`ALTER %s %s SET TAG %s`, r.ObjectType.sql(), name, strings.Join(l, ", "))
+}
+
+func CreateTags(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	for _, t := range tags {
+		if err := runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE TAG IF NOT EXISTS %s.%s.%v COMMENT = 'Managed by Grupr'`,
+			cnf.Database, cnf.Schema, t)); err != nil {
+			return fmt.Errorf("create tag %v: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func DoTagRefs(ctx context.Context, cnf *Config, conn *sql.DB, refs iter.Seq[TagRef], unset bool) error {
+	// Runs statements in batches
+	buf := make([]string, cnf.StmtBatchSize)
+	i := 0
+	for r := range refs {
+		if i == cnf.StmtBatchSize {
+			if err := runMultipleSQL(ctx, cnf, conn, strings.Join(buf, ";"), i); err != nil {
+				return err
+			}
+			i = 0
+		}
+		buf[i] = r.buildSQL(cnf, unset)
+		i++
+	}
+	if i > 0 {
+		if err := runMultipleSQL(ctx, cnf, conn, strings.Join(buf[0:i], ";"), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
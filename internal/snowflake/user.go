@@ -0,0 +1,102 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+// A User is a Snowflake user that grupr grants product roles to: a service account user, or a team member. If grupr
+// provisions users, it creates them when they do not exist yet.
+type User struct {
+	Name             semantics.Ident
+	IsService        bool
+	DefaultRole      semantics.Ident // empty if none
+	DefaultWarehouse semantics.Ident // empty if none
+}
+
+// userState is a user as found in Snowflake
+type userState struct {
+	Name     semantics.Ident
+	Owner    semantics.Ident
+	Disabled bool
+}
+
+func readRSAPublicKey(cnf *Config, name semantics.Ident) (string, error) {
+	b, err := os.ReadFile(filepath.Join(cnf.RSAPublicKeyDir, string(name)+".pub"))
+	if err != nil {
+		return "", err
+	}
+	// Snowflake wants the key without the PEM header and footer
+	var key strings.Builder
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-----") {
+			key.WriteString(line)
+		}
+	}
+	return key.String(), nil
+}
+
+func (u User) buildSQLCreate(cnf *Config) string {
+	props := []string{"TYPE = PERSON"}
+	if u.IsService {
+		props = []string{"TYPE = SERVICE"}
+		if cnf.RSAPublicKeyDir == "" {
+			log.Printf("WARN: creating service user '%v' without an RSA public key, set GRUPR_SNOWFLAKE_RSA_PUBLIC_KEY_DIR", u.Name)
+		} else if key, err := readRSAPublicKey(cnf, u.Name); err != nil {
+			log.Printf("WARN: creating service user '%v' without an RSA public key: %v", u.Name, err)
+		} else {
+			props = append(props, fmt.Sprintf("RSA_PUBLIC_KEY = $$%s$$", key))
+		}
+	}
+	// Properties can not hold IDENTIFIER(), so we quote the identifiers explicitly, to keep their case
+	if u.DefaultRole != "" {
+		props = append(props, "DEFAULT_ROLE = "+u.DefaultRole.Quote())
+	}
+	if u.DefaultWarehouse != "" {
+		props = append(props, "DEFAULT_WAREHOUSE = "+u.DefaultWarehouse.Quote())
+	}
+	return fmt.Sprintf(`CREATE USER IF NOT EXISTS IDENTIFIER($$%s$$) %s`, u.Name, strings.Join(props, " "))
+}
+
+func (u User) create(ctx context.Context, cnf *Config, conn *sql.DB) error {
+	return runSQL(ctx, cnf, conn, u.buildSQLCreate(cnf))
+}
+
+func SetUserDisabled(ctx context.Context, cnf *Config, conn *sql.DB, name semantics.Ident, disabled bool) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER USER IDENTIFIER($$%s$$) SET DISABLED = %s`, name, strings.ToUpper(fmt.Sprint(disabled))))
+}
+
+func queryUsers(ctx context.Context, conn *sql.DB) iter.Seq2[userState, error] {
+	return func(yield func(userState, error) bool) {
+		rows, err := conn.QueryContext(ctx, `SHOW USERS ->> SELECT "name", "owner", "disabled" FROM $1`)
+		if err != nil {
+			yield(userState{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var u userState
+			var disabled string
+			if err = rows.Scan(&u.Name, &u.Owner, &disabled); err != nil {
+				yield(userState{}, fmt.Errorf("queryUsers: error scanning row: %w", err))
+				return
+			}
+			u.Disabled = strings.ToLower(disabled) == "true"
+			if !yield(u, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(userState{}, fmt.Errorf("queryUsers: error after looping over results: %w", err))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package snowflake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
+const testRSAPublicKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAy
+  AbCdEf
+-----END PUBLIC KEY-----
+`
+
+func TestReadRSAPublicKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "SVC_CRM.pub"), []byte(testRSAPublicKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cnf := &Config{RSAPublicKeyDir: dir}
+	got, err := readRSAPublicKey(cnf, "SVC_CRM")
+	if err != nil {
+		t.Fatalf("readRSAPublicKey(): %v", err)
+	}
+	if want := "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAyAbCdEf"; got != want {
+		t.Errorf("readRSAPublicKey() = %q, want %q", got, want)
+	}
+	if _, err := readRSAPublicKey(cnf, "SVC_OTHER"); err == nil {
+		t.Errorf("readRSAPublicKey() without a key file: expected an error")
+	}
+}
+
+func TestUserBuildSQLCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "SVC_CRM.pub"), []byte(testRSAPublicKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		u    User
+		cnf  *Config
+		want string
+	}{
+		{
+			u:    User{Name: "alice@example.com"},
+			cnf:  &Config{},
+			want: `CREATE USER IF NOT EXISTS IDENTIFIER($$"alice@example.com"$$) TYPE = PERSON`,
+		},
+		{
+			u:    User{Name: "alice@example.com", DefaultRole: semantics.Ident("_X_CRM_X_P_X_R"), DefaultWarehouse: semantics.Ident("my wh")},
+			cnf:  &Config{},
+			want: `CREATE USER IF NOT EXISTS IDENTIFIER($$"alice@example.com"$$) TYPE = PERSON DEFAULT_ROLE = "_X_CRM_X_P_X_R" DEFAULT_WAREHOUSE = "my wh"`,
+		},
+		{
+			u:    User{Name: "SVC_CRM", IsService: true},
+			cnf:  &Config{RSAPublicKeyDir: dir},
+			want: `CREATE USER IF NOT EXISTS IDENTIFIER($$"SVC_CRM"$$) TYPE = SERVICE RSA_PUBLIC_KEY = $$MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAyAbCdEf$$`,
+		},
+		{
+			u:    User{Name: "SVC_OTHER", IsService: true}, // no key file
+			cnf:  &Config{RSAPublicKeyDir: dir},
+			want: `CREATE USER IF NOT EXISTS IDENTIFIER($$"SVC_OTHER"$$) TYPE = SERVICE`,
+		},
+		{
+			u:    User{Name: "SVC_CRM", IsService: true},
+			cnf:  &Config{}, // no key dir
+			want: `CREATE USER IF NOT EXISTS IDENTIFIER($$"SVC_CRM"$$) TYPE = SERVICE`,
+		},
+	}
+	for i, test := range tests {
+		if got := test.u.buildSQLCreate(test.cnf); got != test.want {
+			t.Errorf("test %d: buildSQLCreate() = %q, want %q", i, got, test.want)
+		}
+	}
+}
@@ -1,9 +1,206 @@
 package snowflake
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/rwberendsen/grupr/internal/semantics"
+)
+
 type WarehouseDecoded struct {
 	Ident         string   `yaml:"ident"`
 	Mode          string   `yaml:"mode"`
 	SharedBetween []string `yaml:"shared_between,omitempty"`
 	OnlyProd      bool     `yaml:"only_prod",omitempty"`
 	OnlyNonProd   bool     `yaml:"only_non_prod",omitempty"`
+
+	// Properties; grupr creates the warehouse if it does not exist, and keeps the properties that are set in sync
+	WarehouseSize   string  `yaml:"warehouse_size,omitempty"`
+	AutoSuspend     *int    `yaml:"auto_suspend,omitempty"`
+	AutoResume      *bool   `yaml:"auto_resume,omitempty"`
+	ScalingPolicy   string  `yaml:"scaling_policy,omitempty"`
+	MinClusterCount *int    `yaml:"min_cluster_count,omitempty"`
+	MaxClusterCount *int    `yaml:"max_cluster_count,omitempty"`
+	Comment         *string `yaml:"comment,omitempty"`
+}
+
+// WarehouseProps holds warehouse properties; nil means not declared in the YAML, or, for warehouses queried from
+// Snowflake, unknown.
+type WarehouseProps struct {
+	WarehouseSize   *string
+	AutoSuspend     *int
+	AutoResume      *bool
+	ScalingPolicy   *string
+	MinClusterCount *int
+	MaxClusterCount *int
+	Comment         *string
+}
+
+type Warehouse struct {
+	Name            semantics.Ident
+	Owner           semantics.Ident
+	ResourceMonitor semantics.Ident // empty if none
+	WarehouseProps
+}
+
+// normalizeWarehouseSize maps the sizes as they can be written in SQL, and as they appear in the output of SHOW
+// WAREHOUSES, e.g., X-Small or 2X-Large, on a single name; the empty string means the size is invalid.
+func normalizeWarehouseSize(s string) string {
+	s = strings.ToUpper(strings.NewReplacer("-", "", "_", "", " ", "").Replace(s))
+	return map[string]string{
+		"XSMALL":   "XSMALL",
+		"SMALL":    "SMALL",
+		"MEDIUM":   "MEDIUM",
+		"LARGE":    "LARGE",
+		"XLARGE":   "XLARGE",
+		"XXLARGE":  "XXLARGE",
+		"X2LARGE":  "XXLARGE",
+		"2XLARGE":  "XXLARGE",
+		"XXXLARGE": "XXXLARGE",
+		"X3LARGE":  "XXXLARGE",
+		"3XLARGE":  "XXXLARGE",
+		"X4LARGE":  "X4LARGE",
+		"4XLARGE":  "X4LARGE",
+		"X5LARGE":  "X5LARGE",
+		"5XLARGE":  "X5LARGE",
+		"X6LARGE":  "X6LARGE",
+		"6XLARGE":  "X6LARGE",
+	}[s]
+}
+
+func newWarehouseProps(w WarehouseDecoded) (WarehouseProps, error) {
+	p := WarehouseProps{
+		AutoSuspend:     w.AutoSuspend,
+		AutoResume:      w.AutoResume,
+		MinClusterCount: w.MinClusterCount,
+		MaxClusterCount: w.MaxClusterCount,
+		Comment:         w.Comment,
+	}
+	if w.WarehouseSize != "" {
+		s := normalizeWarehouseSize(w.WarehouseSize)
+		if s == "" {
+			return p, fmt.Errorf("invalid warehouse_size '%s'", w.WarehouseSize)
+		}
+		p.WarehouseSize = &s
+	}
+	if w.ScalingPolicy != "" {
+		s := strings.ToUpper(w.ScalingPolicy)
+		if s != "STANDARD" && s != "ECONOMY" {
+			return p, fmt.Errorf("invalid scaling_policy '%s'", w.ScalingPolicy)
+		}
+		p.ScalingPolicy = &s
+	}
+	if p.AutoSuspend != nil && *p.AutoSuspend < 0 {
+		return p, fmt.Errorf("auto_suspend should not be negative")
+	}
+	if p.MinClusterCount != nil && *p.MinClusterCount < 1 || p.MaxClusterCount != nil && *p.MaxClusterCount < 1 {
+		return p, fmt.Errorf("min_cluster_count and max_cluster_count should be at least 1")
+	}
+	if p.MinClusterCount != nil && p.MaxClusterCount != nil && *p.MinClusterCount > *p.MaxClusterCount {
+		return p, fmt.Errorf("min_cluster_count should not exceed max_cluster_count")
+	}
+	if p.Comment != nil && strings.Contains(*p.Comment, "$$") {
+		return p, fmt.Errorf("comment should not contain '$$'")
+	}
+	return p, nil
+}
+
+// buildSQLProps returns the properties in declared that differ from those in actual; pass an empty actual to get
+// all declared properties.
+func (declared WarehouseProps) buildSQLProps(actual WarehouseProps) []string {
+	l := []string{}
+	if declared.WarehouseSize != nil && (actual.WarehouseSize == nil || *actual.WarehouseSize != *declared.WarehouseSize) {
+		l = append(l, fmt.Sprintf("WAREHOUSE_SIZE = '%s'", *declared.WarehouseSize))
+	}
+	if declared.AutoSuspend != nil && (actual.AutoSuspend == nil || *actual.AutoSuspend != *declared.AutoSuspend) {
+		l = append(l, fmt.Sprintf("AUTO_SUSPEND = %d", *declared.AutoSuspend))
+	}
+	if declared.AutoResume != nil && (actual.AutoResume == nil || *actual.AutoResume != *declared.AutoResume) {
+		l = append(l, fmt.Sprintf("AUTO_RESUME = %s", strings.ToUpper(strconv.FormatBool(*declared.AutoResume))))
+	}
+	if declared.ScalingPolicy != nil && (actual.ScalingPolicy == nil || *actual.ScalingPolicy != *declared.ScalingPolicy) {
+		l = append(l, fmt.Sprintf("SCALING_POLICY = '%s'", *declared.ScalingPolicy))
+	}
+	if declared.MinClusterCount != nil && (actual.MinClusterCount == nil || *actual.MinClusterCount != *declared.MinClusterCount) {
+		l = append(l, fmt.Sprintf("MIN_CLUSTER_COUNT = %d", *declared.MinClusterCount))
+	}
+	if declared.MaxClusterCount != nil && (actual.MaxClusterCount == nil || *actual.MaxClusterCount != *declared.MaxClusterCount) {
+		l = append(l, fmt.Sprintf("MAX_CLUSTER_COUNT = %d", *declared.MaxClusterCount))
+	}
+	if declared.Comment != nil && (actual.Comment == nil || *actual.Comment != *declared.Comment) {
+		l = append(l, fmt.Sprintf("COMMENT = $$%s$$", *declared.Comment))
+	}
+	return l
+}
+
+func CreateWarehouse(ctx context.Context, cnf *Config, conn *sql.DB, name semantics.Ident, p WarehouseProps) error {
+	// Note that creating a warehouse also makes it the current warehouse of the session, which is harmless for grupr
+	props := append([]string{"INITIALLY_SUSPENDED = TRUE"}, p.buildSQLProps(WarehouseProps{})...)
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`CREATE WAREHOUSE IF NOT EXISTS IDENTIFIER($$%s$$) WITH %s`, name, strings.Join(props, " ")))
+}
+
+func AlterWarehouse(ctx context.Context, cnf *Config, conn *sql.DB, name semantics.Ident, props []string) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`ALTER WAREHOUSE IDENTIFIER($$%s$$) SET %s`, name, strings.Join(props, ", ")))
+}
+
+func DropWarehouse(ctx context.Context, cnf *Config, conn *sql.DB, name semantics.Ident) error {
+	return runSQL(ctx, cnf, conn, fmt.Sprintf(`DROP WAREHOUSE IF EXISTS IDENTIFIER($$%s$$)`, name))
+}
+
+func QueryWarehouses(ctx context.Context, conn *sql.DB) iter.Seq2[Warehouse, error] {
+	return func(yield func(Warehouse, error) bool) {
+		rows, err := conn.QueryContext(ctx, `SHOW WAREHOUSES ->> SELECT
+    "name"
+  , "owner"
+  , "size"
+  , "auto_suspend"
+  , "auto_resume"
+  , "scaling_policy"
+  , "min_cluster_count"
+  , "max_cluster_count"
+  , "comment"
+  , IFF("resource_monitor" = 'null', NULL, "resource_monitor")
+FROM $1`)
+		if err != nil {
+			yield(Warehouse{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var w Warehouse
+			var size *string
+			var autoResume *string
+			var resourceMonitor *semantics.Ident
+			if err = rows.Scan(&w.Name, &w.Owner, &size, &w.AutoSuspend, &autoResume, &w.ScalingPolicy,
+				&w.MinClusterCount, &w.MaxClusterCount, &w.Comment, &resourceMonitor); err != nil {
+				yield(Warehouse{}, fmt.Errorf("QueryWarehouses: error scanning row: %w", err))
+				return
+			}
+			if resourceMonitor != nil {
+				w.ResourceMonitor = *resourceMonitor
+			}
+			if size != nil {
+				s := normalizeWarehouseSize(*size)
+				w.WarehouseSize = &s
+			}
+			if autoResume != nil {
+				b := strings.ToLower(*autoResume) == "true"
+				w.AutoResume = &b
+			}
+			if w.ScalingPolicy != nil {
+				s := strings.ToUpper(*w.ScalingPolicy)
+				w.ScalingPolicy = &s
+			}
+			if !yield(w, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(Warehouse{}, fmt.Errorf("QueryWarehouses: error after looping over results: %w", err))
+		}
+	}
 }
@@ -0,0 +1,36 @@
+package snowflake
+
+import (
+	"testing"
+)
+
+func TestNormalizeWarehouseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "X-Small", want: "XSMALL"},
+		{s: "XSMALL", want: "XSMALL"},
+		{s: "x_small", want: "XSMALL"},
+		{s: "Small", want: "SMALL"},
+		{s: "Medium", want: "MEDIUM"},
+		{s: "Large", want: "LARGE"},
+		{s: "X-Large", want: "XLARGE"},
+		{s: "2X-Large", want: "XXLARGE"},
+		{s: "XXLARGE", want: "XXLARGE"},
+		{s: "X2LARGE", want: "XXLARGE"},
+		{s: "3X-Large", want: "XXXLARGE"},
+		{s: "X3LARGE", want: "XXXLARGE"},
+		{s: "4X-Large", want: "X4LARGE"},
+		{s: "5X-Large", want: "X5LARGE"},
+		{s: "6X-Large", want: "X6LARGE"},
+		{s: "", want: ""},
+		{s: "Huge", want: ""},
+		{s: "7X-Large", want: ""},
+	}
+	for _, test := range tests {
+		if got := normalizeWarehouseSize(test.s); got != test.want {
+			t.Errorf("normalizeWarehouseSize(%q) = %q, want %q", test.s, got, test.want)
+		}
+	}
+}
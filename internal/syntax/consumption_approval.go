@@ -0,0 +1,17 @@
+package syntax
+
+// A ConsumptionApproval records that the producing product approved a product consuming one of its interfaces
+type ConsumptionApproval struct {
+	InterfaceID `yaml:",inline"`
+	Consumer    string `yaml:"consumer"`        // product id
+	ApprovedBy  string `yaml:"approved_by"`     // user identifier of a member of a team that works on the producing product
+	Until       string `yaml:"until,omitempty"` // date, the last day of the approval
+	Reason      string `yaml:"reason,omitempty"`
+}
+
+func (a *ConsumptionApproval) validate() error {
+	if a.ID == "" || a.ProductID == "" || a.Consumer == "" || a.ApprovedBy == "" {
+		return &FormattingError{"consumption_approval: id, product_id, consumer, and approved_by are required"}
+	}
+	return nil
+}
@@ -5,13 +5,15 @@ import (
 )
 
 type ElmntOr struct {
-	Classes          map[string]Class
-	GlobalUserGroups *GlobalUserGroups `yaml:"global_user_groups,omitempty"`
-	UserGroupMapping *UserGroupMapping `yaml:"user_group_mapping,omitempty"`
-	Product          *Product          `yaml:",omitempty"`
-	Interface        *Interface        `yaml:"interface,omitempty"`
-	ServiceAccount   *ServiceAccount   `yaml:"service_account,omitempty"`
-	Team             *Team             `yaml:"team,omitempty"`
+	Classes             map[string]Class
+	GlobalUserGroups    *GlobalUserGroups    `yaml:"global_user_groups,omitempty"`
+	UserGroupMapping    *UserGroupMapping    `yaml:"user_group_mapping,omitempty"`
+	Product             *Product             `yaml:",omitempty"`
+	Interface           *Interface           `yaml:"interface,omitempty"`
+	ServiceAccount      *ServiceAccount      `yaml:"service_account,omitempty"`
+	Team                *Team                `yaml:"team,omitempty"`
+	Policy              *Policy              `yaml:"policy,omitempty"`
+	ConsumptionApproval *ConsumptionApproval `yaml:"consumption_approval,omitempty"`
 }
 
 func (e ElmntOr) validateAndAdd(g *Grupin) error {
@@ -75,11 +77,28 @@ func (e ElmntOr) validateAndAdd(g *Grupin) error {
 	}
 	if e.Team != nil {
 		nElements += 1
+		if err := e.Team.validate(); err != nil {
+			return err
+		}
 		if _, ok := g.Teams[e.Team.ID]; ok {
 			return &FormattingError{fmt.Sprintf("duplicate team id")}
 		}
 		g.Teams[e.Team.ID] = *e.Team
 	}
+	if e.Policy != nil {
+		nElements += 1
+		if err := e.Policy.validate(); err != nil {
+			return err
+		}
+		g.Policies = append(g.Policies, *e.Policy)
+	}
+	if e.ConsumptionApproval != nil {
+		nElements += 1
+		if err := e.ConsumptionApproval.validate(); err != nil {
+			return err
+		}
+		g.ConsumptionApprovals = append(g.ConsumptionApprovals, *e.ConsumptionApproval)
+	}
 	if nElements != 1 {
 		return &FormattingError{"not exactly one element in ElmntOr"}
 	}
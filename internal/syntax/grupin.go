@@ -9,13 +9,15 @@ import (
 )
 
 type Grupin struct {
-	Classes           map[string]Class
-	GlobalUserGroups  *GlobalUserGroups
-	UserGroupMappings map[string]UserGroupMapping
-	Products          map[string]Product
-	Interfaces        map[InterfaceID]Interface
-	ServiceAccounts   map[string]ServiceAccount
-	Teams             map[string]Team
+	Classes              map[string]Class
+	GlobalUserGroups     *GlobalUserGroups
+	UserGroupMappings    map[string]UserGroupMapping
+	Products             map[string]Product
+	Interfaces           map[InterfaceID]Interface
+	ServiceAccounts      map[string]ServiceAccount
+	Teams                map[string]Team
+	Policies             []Policy
+	ConsumptionApprovals []ConsumptionApproval
 }
 
 func NewGrupin(r io.Reader) (Grupin, error) {
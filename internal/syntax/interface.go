@@ -3,5 +3,7 @@ package syntax
 type Interface struct {
 	ID                string `yaml:"id"`
 	ProductID         string `yaml:"product_id"`
+	Lifecycle         string `yaml:"lifecycle,omitempty"`   // active (default), deprecated, or retired
+	SunsetDate        string `yaml:"sunset_date,omitempty"` // last day deprecated interface can be consumed
 	InterfaceMetadata `yaml:",inline"`
 }
@@ -7,5 +7,10 @@ type InterfaceMetadata struct {
 	ObjectsExclude []string `yaml:"objects_exclude,omitempty"`
 	MaskColumns    []string `yaml:"mask_columns,omitempty"`
 	HashColumns    []string `yaml:"hash_columns,omitempty"`
-	ForProduct     *string  `yaml:"for_product",omitempty"`
+	// The classification of the interface as consumers see it, after masking and hashing
+	MaskedClassification string   `yaml:"masked_classification,omitempty"`
+	ForProduct           *string  `yaml:"for_product",omitempty"`
+	Visibility           string   `yaml:"visibility,omitempty"`             // public, restricted, or private
+	AllowedConsumers     []string `yaml:"allowed_consumers,flow,omitempty"` // product ids, for restricted interfaces
+	HideDTAPs            []string `yaml:"hide_dtaps,flow,omitempty"`        // dtaps that can not be consumed, e.g., a sandbox
 }
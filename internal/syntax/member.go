@@ -0,0 +1,42 @@
+package syntax
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Member of a team is either just a user identifier, or a mapping with the identifier, and the period in which the
+// user is a member, e.g., for contractors and temporary helpers.
+type Member struct {
+	ID     string `yaml:"id"`
+	From   string `yaml:"from,omitempty"`  // date, e.g., 2025-01-31
+	Until  string `yaml:"until,omitempty"` // date, the last day of the membership
+	Reason string `yaml:"reason,omitempty"`
+}
+
+func (m *Member) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&m.ID)
+	}
+	// Decoding a node does not honour KnownFields of the decoder, so we check the fields ourselves
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i < len(value.Content); i += 2 {
+			switch k := value.Content[i].Value; k {
+			case "id", "from", "until", "reason":
+			default:
+				return &FormattingError{fmt.Sprintf("member: unknown field '%s'", k)}
+			}
+		}
+	}
+	type plain Member
+	return value.Decode((*plain)(m))
+}
+
+func (m Member) MarshalYAML() (interface{}, error) {
+	if m.From == "" && m.Until == "" && m.Reason == "" {
+		return m.ID, nil
+	}
+	type plain Member
+	return plain(m), nil
+}
@@ -0,0 +1,34 @@
+package syntax
+
+import (
+	"fmt"
+)
+
+// A MembersSource is an export of a group from an identity provider, or from HR data, to source the members of a
+// team from, rather than listing them by hand.
+type MembersSource struct {
+	Path        string            `yaml:"path,omitempty"`
+	URL         string            `yaml:"url,omitempty"`
+	Format      string            `yaml:"format"`                 // csv, json, or scim
+	Column      string            `yaml:"column,omitempty"`       // csv: column with user names, by default user_name
+	Group       string            `yaml:"group,omitempty"`        // scim: display name of the group, if the export has more than one
+	UserMapping map[string]string `yaml:"user_mapping,omitempty"` // k: user name in the source; v: Snowflake user
+}
+
+func (s *MembersSource) validate() error {
+	if (s.Path == "") == (s.URL == "") {
+		return &FormattingError{"members_from: specify either path or url"}
+	}
+	switch s.Format {
+	case "csv", "json", "scim":
+	default:
+		return &FormattingError{fmt.Sprintf("members_from: invalid format '%s', use csv, json, or scim", s.Format)}
+	}
+	if s.Column != "" && s.Format != "csv" {
+		return &FormattingError{"members_from: column is only used with format csv"}
+	}
+	if s.Group != "" && s.Format != "scim" {
+		return &FormattingError{"members_from: group is only used with format scim"}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package syntax
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Policy struct {
+	Rule     string   `yaml:"rule"`
+	Level    string   `yaml:"level"`              // error, warn, or off
+	Products []string `yaml:"products,omitempty"` // if empty, the policy holds for all products
+	Max      int      `yaml:"max,omitempty"`      // for rules that limit a number
+}
+
+func (p *Policy) validate() error {
+	if p.Rule == "" {
+		return &FormattingError{"policy: rule is required"}
+	}
+	if !slices.Contains([]string{"error", "warn", "off"}, p.Level) {
+		return &FormattingError{fmt.Sprintf("policy '%s': level should be one of error, warn, off", p.Rule)}
+	}
+	if p.Max < 0 {
+		return &FormattingError{fmt.Sprintf("policy '%s': max should not be negative", p.Rule)}
+	}
+	return nil
+}
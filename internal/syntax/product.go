@@ -14,9 +14,13 @@ type Product struct {
 	UserGroupRenderings map[string]Rendering `yaml:"user_group_renderings,omitempty"`
 	UserGroupColumn     string               `yaml:"user_group_column,omitempty"`
 	BlockCentralTeams   bool                 `yaml:"block_central_teams,omitempty"`
+	ConfiscateAfter     *int                 `yaml:"confiscate_after,omitempty"` // days; overrides the --confiscate-after flag
 }
 
 func (p *Product) validate() error {
+	if p.ConfiscateAfter != nil && *p.ConfiscateAfter < 0 {
+		return fmt.Errorf("product '%s': confiscate_after should not be negative", p.ID)
+	}
 	for k, v := range p.DTAPRenderings {
 		if err := v.validate(); err != nil {
 			return fmt.Errorf("product '%s', dtap_rendering: '%s': %w", p.ID, k, err)
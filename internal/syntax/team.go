@@ -1,9 +1,24 @@
 package syntax
 
+import (
+	"fmt"
+)
+
 type Team struct {
-	ID          string   `yaml:"id"`
-	Members     []string `yaml:"members,omitempty"`
-	WorkOn      []string `yaml:"work_on,omitempty"`
-	IsCentral   bool     `yaml:"is_central,omitempty"`
-	OnlyNonProd bool     `yaml:"only_non_prod,omitempty"`
+	ID          string         `yaml:"id"`
+	Members     []Member       `yaml:"members,omitempty"`
+	MembersFrom *MembersSource `yaml:"members_from,omitempty"`
+	WorkOn      []string       `yaml:"work_on,omitempty"`
+	IsCentral   bool           `yaml:"is_central,omitempty"`
+	OnlyNonProd bool           `yaml:"only_non_prod,omitempty"`
+	OnCallFor   []string       `yaml:"on_call_for,omitempty"`
+}
+
+func (t *Team) validate() error {
+	if t.MembersFrom != nil {
+		if err := t.MembersFrom.validate(); err != nil {
+			return fmt.Errorf("team '%s': %w", t.ID, err)
+		}
+	}
+	return nil
 }